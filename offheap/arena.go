@@ -0,0 +1,166 @@
+package offheap
+
+// BytesArena stores variable-length byte strings off-heap. Strings are appended one after another
+// into a single mapping and addressed by index, with offsets of their ends kept in a separate array.
+// Stored strings can't be changed or removed individually.
+type BytesArena struct {
+	data *Array[byte]
+	ends *Array[uint64]
+}
+
+// NewBytesArena allocates an arena with capacity for count strings of total size bytes.
+func NewBytesArena(count, bytes int) *BytesArena {
+	return must(NewBytesArenaE(count, bytes))
+}
+
+// NewBytesArenaE is like NewBytesArena, but returns an error instead of panicking.
+func NewBytesArenaE(count, bytes int) (*BytesArena, error) {
+	return NewBytesArenaWithOptions(count, bytes, Options{})
+}
+
+// NewBytesArenaWithOptions is like NewBytesArenaE, with the arena mapped according to opts.
+// Unlike arrays, the arena grows according to DefaultGrowthPolicy unless opts define a growth policy.
+func NewBytesArenaWithOptions(count, bytes int, opts Options) (*BytesArena, error) {
+	if opts.Growth == (GrowthPolicy{}) {
+		opts.Growth = DefaultGrowthPolicy
+	}
+
+	data, err := newArray[byte](arenaType, bytes, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ends, err := newArray[uint64](arenaType, count, opts)
+	if err != nil {
+		data.Dealloc()
+		return nil, err
+	}
+
+	return &BytesArena{data: data, ends: ends}, nil
+}
+
+// Len returns the number of stored strings
+func (o *BytesArena) Len() int {
+	return o.ends.Len()
+}
+
+// Size returns the total size of stored strings in bytes
+func (o *BytesArena) Size() int {
+	return o.data.Len()
+}
+
+func (o *BytesArena) Options() Options {
+	return o.data.Options()
+}
+
+func (o *BytesArena) Dealloc() {
+	if err := o.Close(); err != nil {
+		panic(err)
+	}
+}
+
+func (o *BytesArena) Close() error {
+	err := o.data.Close()
+	if endsErr := o.ends.Close(); err == nil {
+		err = endsErr
+	}
+	return err
+}
+
+func (o *BytesArena) Freeze() error {
+	if err := o.data.Freeze(); err != nil {
+		return err
+	}
+	return o.ends.Freeze()
+}
+
+func (o *BytesArena) Frozen() bool {
+	return o.ends.Frozen()
+}
+
+// Get returns the string at index i. The returned slice points into the arena, so it must not be modified,
+// and is only valid until the arena is grown, trimmed or released.
+func (o *BytesArena) Get(i int) []byte {
+	end := o.ends.Get(i)
+
+	var start uint64
+	if i > 0 {
+		start = o.ends.Get(i - 1)
+	}
+	return o.data.slice[start:end:end]
+}
+
+// GetString returns a copy of the string at index i
+func (o *BytesArena) GetString(i int) string {
+	return string(o.Get(i))
+}
+
+// Append stores b and returns its index. The arena is grown as needed, panicking if it can't be.
+func (o *BytesArena) Append(b []byte) int {
+	return must(o.AppendE(b))
+}
+
+// AppendE is like Append, but returns an error if the arena cannot be grown. On failure the arena is left intact.
+func (o *BytesArena) AppendE(b []byte) (int, error) {
+	if o.Frozen() {
+		return 0, ErrFrozen
+	}
+
+	if err := o.Reserve(1, len(b)); err != nil {
+		return 0, err
+	}
+
+	o.data.AppendSlice(b)
+	o.ends.Append(uint64(o.data.Len()))
+	return o.ends.Len() - 1, nil
+}
+
+// AppendString is like Append, but stores s
+func (o *BytesArena) AppendString(s string) int {
+	return o.Append([]byte(s))
+}
+
+// Reserve makes sure at least count more strings of total size bytes can be added without exceeding capacity
+func (o *BytesArena) Reserve(count, bytes int) error {
+	if err := o.data.Reserve(bytes); err != nil {
+		return err
+	}
+	return o.ends.Reserve(count)
+}
+
+func (o *BytesArena) TrimToSize() *BytesArena {
+	return must(o.TrimToSizeE())
+}
+
+// TrimToSizeE is like TrimToSize, but returns an error instead of panicking. On failure the arena is left intact.
+func (o *BytesArena) TrimToSizeE() (*BytesArena, error) {
+	data, err := o.data.TrimToSizeE()
+	if err != nil {
+		return nil, err
+	}
+
+	ends, err := o.ends.TrimToSizeE()
+	if err != nil {
+		// Data was only trimmed, so nothing is lost keeping it
+		o.data = data
+		return nil, err
+	}
+
+	return &BytesArena{data: data, ends: ends}, nil
+}
+
+// check returns ErrSnapshot if ends of strings are not ordered within data, as in a corrupted snapshot
+func (o *BytesArena) check() error {
+	var prev uint64
+	for _, end := range o.ends.slice {
+		if end < prev {
+			return snapshotError("arena string ends at %d before %d", end, prev)
+		}
+		prev = end
+	}
+
+	if prev != uint64(o.data.Len()) {
+		return snapshotError("arena strings end at %d, not %d", prev, o.data.Len())
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package offheap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytesArena(t *testing.T) {
+	a := NewBytesArena(1, 4)
+
+	assert.Equal(t, 0, a.AppendString("first"))
+	assert.Equal(t, 1, a.Append(nil))
+	assert.Equal(t, 2, a.AppendString("third"))
+
+	for i := 3; i < 1000; i++ {
+		assert.Equal(t, i, a.AppendString(fmt.Sprint(i)))
+	}
+
+	assert.Equal(t, 1000, a.Len())
+	assert.Equal(t, "first", a.GetString(0))
+	assert.Empty(t, a.Get(1))
+	assert.Equal(t, []byte("third"), a.Get(2))
+	assert.Equal(t, "999", a.GetString(999))
+
+	a = a.TrimToSize()
+	assert.Equal(t, a.Size(), a.data.Cap())
+	assert.Equal(t, "first", a.GetString(0))
+
+	require.NoError(t, a.Freeze())
+	_, err := a.AppendE([]byte("x"))
+	assert.ErrorIs(t, err, ErrFrozen)
+
+	a.Dealloc()
+}
@@ -1,54 +1,393 @@
 package offheap
 
 import (
+	"fmt"
+	"golang.org/x/tools/container/intsets"
+	"os"
 	"reflect"
 	"syscall"
 	"unsafe"
 )
 
 type array struct {
-	sz  uintptr
-	cap int
+	sz   uintptr
+	cap  int
+	data unsafe.Pointer // Start of the mapping, nil if nothing is mapped
+
+	file   *os.File // Backing file, nil for anonymous memory
+	offset int64    // Offset of the mapping in backing file
+	prot   int
+
+	opts Options
+
+	frozen bool
+	state  state
+
+	typ   string    // Element type name, for accounting
+	stack []uintptr // Allocation stack, recorded in debug mode
 }
 
 func (o array) Cap() int {
 	return o.cap
 }
 
-func (o array) allocSlice(len int) reflect.SliceHeader {
+func (o array) size() uintptr {
+	return o.sz * uintptr(o.cap)
+}
+
+func (o *array) allocSlice(len int) (err error) {
+	if len < 0 {
+		return mapError("mmap", 0, syscall.EINVAL)
+	}
+
+	o.cap = len
+	if len == 0 {
+		o.data = nil // Copies of a mapped array keep its pointer
+		return nil
+	}
+
 	noFd := -1
 
-	data, _, errno := syscall.Syscall6(
-		syscall.SYS_MMAP,
-		0,
-		uintptr(len)*o.sz,
+	o.data, err = mmap(
+		o.size(),
 		syscall.PROT_READ|syscall.PROT_WRITE,
-		syscall.MAP_ANON|syscall.MAP_PRIVATE,
-		uintptr(noFd),
+		syscall.MAP_ANON|syscall.MAP_PRIVATE|o.mapFlags(),
+		noFd,
 		0,
 	)
-	if errno != 0 {
-		panic(errno)
+	if err != nil {
+		return err
 	}
 
-	return reflect.SliceHeader{
-		Data: data,
-		Len:  0,
-		Cap:  len,
+	account(o.typ, 1, int64(o.size()))
+
+	if err = o.advise(0); err != nil {
+		_ = o.deallocSlice()
 	}
+	return
 }
 
-func (o array) deallocSlice(p unsafe.Pointer) {
-	hdr := (*reflect.SliceHeader)(p)
+func (o *array) deallocSlice() (err error) {
+	if o.data != nil {
+		if err = munmap(o.data, o.size()); err == nil {
+			account(o.typ, -1, -int64(o.size()))
+		}
+		o.data = nil
+	}
+	return
+}
 
-	_, _, errno := syscall.Syscall(
-		syscall.SYS_MUNMAP,
-		hdr.Data,
-		o.sz*uintptr(o.cap),
-		0,
-	)
+// remapCopy maps a new region for size elements, copies the first len elements and releases the old mapping
+func (o *array) remapCopy(size int, len int) error {
+	target := *o
+	if o.file != nil {
+		if err := target.mapFile(size); err != nil {
+			return err
+		}
+	} else if err := target.allocSlice(size); err != nil {
+		return err
+	}
+
+	if o.file == nil && len > 0 {
+		n := o.sz * uintptr(len)
+		copy(unsafe.Slice((*byte)(target.data), n), unsafe.Slice((*byte)(o.data), n))
+	}
+
+	if err := o.deallocSlice(); err != nil {
+		_ = target.deallocSlice()
+		return err
+	}
+
+	*o = target
+	return nil
+}
+
+// Array is an off-heap array of fixed-size values.
+// T must not contain pointers, as the mapped memory is not scanned by GC.
+type Array[T any] struct {
+	array
+	slice []T
+}
+
+// NewArray allocates an off-heap array with capacity for size elements.
+// Panics if T contains pointers or is zero-sized.
+func NewArray[T any](size int) *Array[T] {
+	return must(NewArrayE[T](size))
+}
+
+// NewArrayE is like NewArray, but returns an error instead of panicking.
+// Mapping failures are reported as *MapError.
+func NewArrayE[T any](size int) (*Array[T], error) {
+	return NewArrayWithOptions[T](size, Options{})
+}
+
+// NewArrayWithOptions allocates an off-heap array with capacity for size elements, mapped according to opts.
+func NewArrayWithOptions[T any](size int, opts Options) (*Array[T], error) {
+	if err := checkElem[T](); err != nil {
+		return nil, err
+	}
+
+	return newArray[T](typeName[T](), size, opts)
+}
+
+// newArray allocates an array accounted in Stats under typ
+func newArray[T any](typ string, size int, opts Options) (*Array[T], error) {
+	var kEl T
+
+	base := array{sz: unsafe.Sizeof(kEl), opts: opts, typ: typ}
+	if err := base.allocSlice(size); err != nil {
+		return nil, err
+	}
+
+	return track(&Array[T]{
+		array: base,
+		slice: unsafe.Slice((*T)(base.data), size)[:0],
+	}), nil
+}
+
+func (o *Array[T]) Len() int {
+	o.checkLive()
+
+	return len(o.slice)
+}
+
+func (o *Array[T]) Dealloc() {
+	if err := o.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// Close releases the array. File-backed arrays are flushed first, see OpenArrayFile.
+func (o *Array[T]) Close() error {
+	o.checkDealloc()
+	defer func() { o.state = deallocated }()
+
+	if o.file != nil {
+		return o.closeFile()
+	}
+
+	o.slice = nil
+	return o.deallocSlice()
+}
+
+func (o *Array[T]) Insert(i int, v T) {
+	o.checkMutable()
+	o.autoGrow(1)
+	o.checkCapacity(len(o.slice), 1)
+
+	if i == o.Len() {
+		o.slice = append(o.slice, v)
+		return
+	}
+
+	o.slice = append(o.slice[:i+1], o.slice[i:]...)
+	o.slice[i] = v
+}
+
+func (o *Array[T]) Get(i int) T {
+	o.checkLive()
+
+	return o.slice[i]
+}
+
+func (o *Array[T]) Set(i int, val T) {
+	o.checkMutable()
+
+	o.slice[i] = val
+}
+
+func (o *Array[T]) Swap(i, j int) {
+	o.checkMutable()
+
+	slice := o.slice
+	slice[i], slice[j] = slice[j], slice[i]
+}
+
+// Append add an element to the end. If the array is full, it is grown according to Options.Growth,
+// otherwise it is a caller's responsibility to Grow() underlying slice if needed.
+// Building with `offheapcheck' tag turns appending beyond capacity into a panic.
+func (o *Array[T]) Append(v T) {
+	o.checkMutable()
+	o.autoGrow(1)
+	o.checkCapacity(len(o.slice), 1)
+
+	o.slice = append(o.slice, v)
+}
+
+// Remove removes an element at index, preserving the order of remaining ones.
+// It is a caller's responsibility to call TrimToSize() for reclaiming space.
+func (o *Array[T]) Remove(i int) {
+	o.RemoveRange(i, i+1)
+}
+
+func (o *Array[T]) Grow(size int) *Array[T] {
+	return must(o.GrowE(size))
+}
+
+// GrowE is like Grow, but returns an error instead of panicking. On failure the array is left intact.
+func (o *Array[T]) GrowE(size int) (*Array[T], error) {
+	o.checkLive()
+
+	if o.frozen {
+		return nil, ErrFrozen
+	}
+
+	return o.resize(size)
+}
+
+func (o *Array[T]) TrimToSize() *Array[T] {
+	return must(o.TrimToSizeE())
+}
+
+// TrimToSizeE is like TrimToSize, but returns an error instead of panicking. On failure the array is left intact.
+func (o *Array[T]) TrimToSizeE() (*Array[T], error) {
+	o.checkLive()
+
+	if o.frozen {
+		return nil, ErrFrozen
+	}
+
+	return o.resize(len(o.slice))
+}
+
+// resize moves the mapping to a new array with capacity for size elements
+func (o *Array[T]) resize(size int) (*Array[T], error) {
+	target := &Array[T]{array: o.array, slice: o.slice}
+	if err := target.resizeInPlace(size); err != nil {
+		return nil, err
+	}
+
+	o.array = array{sz: o.sz, typ: o.typ, stack: o.stack, state: moved}
+	o.slice = nil
+	return track(target), nil
+}
+
+// resizeInPlace changes capacity to size elements, but no less than the current length.
+// The mapping is resized in place where supported, avoiding the copy. On failure the array is left intact,
+// except for failing to apply Options to a moved mapping, which is reported with the array already resized.
+func (o *Array[T]) resizeInPlace(size int) error {
+	if size < len(o.slice) {
+		size = len(o.slice)
+	}
+
+	if o.file != nil {
+		if err := o.extendFile(size); err != nil {
+			return err
+		}
+	}
+
+	// Appending past capacity moves the slice to Go heap, so the mapping holds at most cap elements
+	mapped := len(o.slice)
+	if mapped > o.cap {
+		mapped = o.cap
+	}
+
+	oldData, oldCap := o.data, o.cap
+	err := o.remap(size, mapped)
+	if o.data == oldData && o.cap == oldCap {
+		// Mapping is intact
+		return err
+	}
+
+	// The slice must follow the mapping even if remap failed afterwards, as the old one may be gone
+	slice := o.slice
+	o.slice = unsafe.Slice((*T)(o.data), size)[:len(slice)]
+	if mapped < len(slice) {
+		copy(o.slice, slice)
+	}
+	return err
+}
+
+// Values calls callback once for each distinct value, in order of first occurrence.
+// Values must be comparable.
+func (o *Array[T]) Values(callback func(T)) {
+	o.checkLive()
+
+	// Element types of per-type arrays are tracked without boxing, other ones only have to be comparable
+	switch slice := any(o.slice).(type) {
+	case []int:
+		distinctInts(slice, any(callback).(func(int)))
+	case []uint16:
+		distinctInts(slice, any(callback).(func(uint16)))
+	case []uint32:
+		distinctInts(slice, any(callback).(func(uint32)))
+	case []uint64:
+		distinct(slice, any(callback).(func(uint64)))
+	default:
+		uniq := make(map[interface{}]struct{})
+		for _, v := range o.slice {
+			if _, seen := uniq[v]; !seen {
+				uniq[v] = struct{}{}
+				callback(v)
+			}
+		}
+	}
+}
+
+// distinctInts calls callback once for each distinct value of slice, keeping seen values in a bit set
+func distinctInts[T int | uint16 | uint32](slice []T, callback func(T)) {
+	var uniq intsets.Sparse
+	for _, v := range slice {
+		if uniq.Insert(int(v)) {
+			callback(v)
+		}
+	}
+}
+
+// distinct calls callback once for each distinct value of slice
+func distinct[T comparable](slice []T, callback func(T)) {
+	uniq := make(map[T]struct{})
+	for _, v := range slice {
+		if _, seen := uniq[v]; !seen {
+			uniq[v] = struct{}{}
+			callback(v)
+		}
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func typeName[T any]() string {
+	var kEl T
+	return reflect.TypeOf(&kEl).Elem().String()
+}
+
+func checkElem[T any]() error {
+	var kEl T
+
+	typ := reflect.TypeOf(&kEl).Elem()
+	if hasPointers(typ) {
+		return fmt.Errorf("offheap: %v contains pointers and cannot be stored off-heap", typ)
+	}
+	if unsafe.Sizeof(kEl) == 0 {
+		return fmt.Errorf("offheap: %v is zero-sized", typ)
+	}
+
+	return nil
+}
 
-	if errno != 0 {
-		panic(errno)
+func hasPointers(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Array:
+		return typ.Len() > 0 && hasPointers(typ.Elem())
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			if hasPointers(typ.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	default:
+		return true
 	}
 }
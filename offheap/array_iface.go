@@ -1,80 +1,338 @@
 package offheap
 
-import "unsafe"
+import (
+	"math"
+	"reflect"
+)
 
-// ArrayInterface is an off-heap array if interface{} values
+// ArrayInterface is an off-heap array of interface{} values.
+//
+// Interface values can't be stored in mapped memory, as GC does not scan it and would collect anything
+// referenced only from there. Instead, the array holds handles to a heap-side table of values.
+// Comparable values other than NaN are interned, so the heap keeps a single copy of each distinct value however many
+// times it is stored, and the GC only has to scan the distinct ones.
 type ArrayInterface struct {
-	array
-	slice []interface{}
+	handles *Array[handle]
+	table   *handleTable
 }
 
 func NewArrayInterface(size int) *ArrayInterface {
-	var kEl interface{}
+	return must(NewArrayInterfaceE(size))
+}
 
-	base := array{unsafe.Sizeof(kEl), size}
+func NewArrayInterfaceE(size int) (*ArrayInterface, error) {
+	return NewArrayInterfaceWithOptions(size, Options{})
+}
 
-	mapped := base.allocSlice(size)
-	return &ArrayInterface{
-		array: base,
-		slice: *(*[]interface{})(unsafe.Pointer(&mapped)), //nolint:govet
+func NewArrayInterfaceWithOptions(size int, opts Options) (*ArrayInterface, error) {
+	handles, err := newArray[handle](interfaceType, size, opts)
+	if err != nil {
+		return nil, err
 	}
+
+	return &ArrayInterface{
+		handles: handles,
+		table:   newHandleTable(),
+	}, nil
 }
 
 func (o *ArrayInterface) Len() int {
-	return len(o.slice)
+	return o.handles.Len()
+}
+
+func (o *ArrayInterface) Cap() int {
+	return o.handles.Cap()
+}
+
+func (o *ArrayInterface) Options() Options {
+	return o.handles.Options()
 }
 
 func (o *ArrayInterface) Dealloc() {
-	o.deallocSlice(unsafe.Pointer(&o.slice))
-	o.slice = nil
+	o.handles.Dealloc()
+	o.table = nil
 }
 
-func (o *ArrayInterface) Insert(i int, v interface{}) {
-	if i == o.Len() {
-		o.slice = append(o.slice, v)
-		return
-	}
+func (o *ArrayInterface) Close() error {
+	err := o.handles.Close()
+	o.table = nil
+	return err
+}
+
+func (o *ArrayInterface) Freeze() error {
+	return o.handles.Freeze()
+}
 
-	o.slice = append(o.slice[:i+1], o.slice[i:]...)
-	o.slice[i] = v
+func (o *ArrayInterface) Frozen() bool {
+	return o.handles.Frozen()
+}
+
+func (o *ArrayInterface) Insert(i int, v interface{}) {
+	o.handles.checkMutable()
+	o.handles.Insert(i, o.table.acquire(v))
 }
 
 func (o *ArrayInterface) Get(i int) interface{} {
-	return o.slice[i]
+	h := o.handles.Get(i)
+	return o.table.values[h]
 }
 
 func (o *ArrayInterface) Set(i int, val interface{}) {
-	o.slice[i] = val
+	o.handles.checkMutable()
+
+	prev := o.handles.Get(i)
+	o.handles.Set(i, o.table.acquire(val))
+	o.table.release(prev)
 }
 
 func (o *ArrayInterface) Swap(i, j int) {
-	slice := o.slice
-	slice[i], slice[j] = slice[j], slice[i]
+	o.handles.Swap(i, j)
+}
+
+// Reserve makes sure at least n more elements can be added without exceeding capacity, see Array.Reserve
+func (o *ArrayInterface) Reserve(n int) error {
+	return o.handles.Reserve(n)
 }
 
-// Append add an element to the end. It is a caller's responsibility to Grow() underlying slice if needed.
+// Append add an element to the end. If the array is full, it is grown according to Options.Growth,
+// otherwise it is a caller's responsibility to Grow() underlying slice if needed.
 func (o *ArrayInterface) Append(v interface{}) {
-	o.slice = append(o.slice, v)
+	o.handles.checkMutable()
+	o.handles.Append(o.table.acquire(v))
 }
 
-// Remove removes an element at index. It is a caller's responsibility to call TrimToSize() for reclaiming space.
+// Remove removes an element at index, preserving the order of remaining ones.
+// It is a caller's responsibility to call TrimToSize() for reclaiming space.
 func (o *ArrayInterface) Remove(i int) {
-	o.slice[i] = o.slice[o.Len()-1]
-	o.slice = o.slice[:o.Len()-1]
+	o.RemoveRange(i, i+1)
+}
+
+// AppendSlice adds all elements of vs to the end, see Array.AppendSlice
+func (o *ArrayInterface) AppendSlice(vs []interface{}) {
+	o.handles.checkMutable()
+	o.handles.AppendSlice(o.table.acquireSlice(vs))
+}
+
+// InsertSlice inserts all elements of vs at index i, see Array.InsertSlice
+func (o *ArrayInterface) InsertSlice(i int, vs []interface{}) {
+	o.handles.checkMutable()
+	o.handles.InsertSlice(i, o.table.acquireSlice(vs))
+}
+
+// CopyFrom overwrites elements starting at index i with elements [from, to) of other, see Array.CopyFrom
+func (o *ArrayInterface) CopyFrom(i int, other *ArrayInterface, from, to int) {
+	o.handles.checkMutable()
+
+	if other != o {
+		for k := from; k < to; k++ {
+			o.Set(i+k-from, other.Get(k))
+		}
+		return
+	}
+
+	// Sharing a table, retain copied handles before releasing overwritten ones, as ranges may overlap
+	src := o.handles.slice[from:to]
+	dst := o.handles.slice[i : i+len(src)]
+	for _, h := range src {
+		o.table.retain(h)
+	}
+	for _, h := range dst {
+		o.table.release(h)
+	}
+	o.handles.CopyFrom(i, o.handles, from, to)
+}
+
+// Fill sets elements [from, to) to v
+func (o *ArrayInterface) Fill(from, to int, v interface{}) {
+	o.handles.checkMutable()
+
+	for i := from; i < to; i++ {
+		o.Set(i, v)
+	}
+}
+
+// RemoveRange removes elements [from, to), preserving the order of remaining ones, see Array.RemoveRange
+func (o *ArrayInterface) RemoveRange(from, to int) {
+	o.handles.checkMutable()
+
+	for _, h := range o.handles.slice[from:to] {
+		o.table.release(h)
+	}
+	o.handles.RemoveRange(from, to)
 }
 
 func (o *ArrayInterface) Grow(size int) *ArrayInterface {
-	target := NewArrayInterface(size)
-	target.slice = append(target.slice, o.slice...)
+	return must(o.GrowE(size))
+}
 
-	o.Dealloc()
-	return target
+// GrowE is like Grow, but returns an error instead of panicking. On failure the array is left intact.
+func (o *ArrayInterface) GrowE(size int) (*ArrayInterface, error) {
+	handles, err := o.handles.GrowE(size)
+	if err != nil {
+		return nil, err
+	}
+	return o.moveTo(handles), nil
 }
 
 func (o *ArrayInterface) TrimToSize() *ArrayInterface {
-	target := NewArrayInterface(len(o.slice))
-	target.slice = append(target.slice, o.slice[:len(o.slice)]...)
+	return must(o.TrimToSizeE())
+}
+
+// TrimToSizeE is like TrimToSize, but returns an error instead of panicking. On failure the array is left intact.
+func (o *ArrayInterface) TrimToSizeE() (*ArrayInterface, error) {
+	handles, err := o.handles.TrimToSizeE()
+	if err != nil {
+		return nil, err
+	}
+	return o.moveTo(handles), nil
+}
+
+func (o *ArrayInterface) moveTo(handles *Array[handle]) *ArrayInterface {
+	target := &ArrayInterface{
+		handles: handles,
+		table:   o.table,
+	}
 
-	o.Dealloc()
+	o.table = nil
 	return target
 }
+
+// Values calls callback once for each distinct value, in order of first occurrence.
+// Values which are not comparable are never considered equal.
+func (o *ArrayInterface) Values(callback func(interface{})) {
+	o.handles.checkLive()
+
+	seen := make([]bool, len(o.table.values))
+	for _, h := range o.handles.slice {
+		if !seen[h] {
+			seen[h] = true
+			callback(o.table.values[h])
+		}
+	}
+}
+
+// handle identifies a value in handleTable. Zero handle is reserved for nil.
+type handle = uint32
+
+// handleTable keeps reference counted values of ArrayInterface on the heap
+type handleTable struct {
+	values []interface{}
+	refs   []uint32
+
+	index map[interface{}]handle // Handles of comparable values
+	free  []handle
+}
+
+func newHandleTable() *handleTable {
+	return &handleTable{
+		values: []interface{}{nil},
+		refs:   []uint32{0},
+		index:  map[interface{}]handle{},
+	}
+}
+
+func (t *handleTable) acquire(v interface{}) handle {
+	if v == nil {
+		return 0
+	}
+
+	h, found, comparable := t.lookup(v)
+	if found {
+		t.refs[h]++
+		return h
+	}
+
+	if n := len(t.free); n > 0 {
+		h = t.free[n-1]
+		t.free = t.free[:n-1]
+		t.values[h] = v
+		t.refs[h] = 1
+	} else {
+		if uint64(len(t.values)) > uint64(^handle(0)) {
+			panic("offheap: too many distinct values in ArrayInterface")
+		}
+
+		h = handle(len(t.values))
+		t.values = append(t.values, v)
+		t.refs = append(t.refs, 1)
+	}
+
+	if comparable {
+		t.index[v] = h
+	}
+	return h
+}
+
+func (t *handleTable) acquireSlice(vs []interface{}) []handle {
+	handles := make([]handle, len(vs))
+	for i, v := range vs {
+		handles[i] = t.acquire(v)
+	}
+	return handles
+}
+
+func (t *handleTable) retain(h handle) {
+	if h != 0 {
+		t.refs[h]++
+	}
+}
+
+func (t *handleTable) release(h handle) {
+	if h == 0 {
+		return
+	}
+
+	if t.refs[h]--; t.refs[h] > 0 {
+		return
+	}
+
+	// Only an interned value has an index entry, pointing at its single handle
+	if v := t.values[h]; internable(reflect.ValueOf(v)) && t.index[v] == h {
+		delete(t.index, v)
+	}
+
+	t.values[h] = nil
+	t.free = append(t.free, h)
+}
+
+// lookup finds an interned value, see internable
+func (t *handleTable) lookup(v interface{}) (h handle, found bool, comparable bool) {
+	if !internable(reflect.ValueOf(v)) {
+		return 0, false, false
+	}
+
+	h, found = t.index[v]
+	return h, found, true
+}
+
+// internable tells if v can be a map key equal to itself. Values holding slices, maps or functions
+// can't be hashed, and NaN is not equal to itself, so its index entries could never be found and removed.
+func internable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return !math.IsNaN(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return !math.IsNaN(real(c)) && !math.IsNaN(imag(c))
+	case reflect.Interface:
+		return v.IsNil() || internable(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !internable(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !internable(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,95 @@
+package offheap
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ifaceProbe struct {
+	name string
+}
+
+func TestArrayInterface_GC(t *testing.T) {
+	n := 10_000
+
+	a := NewArrayInterface(n)
+	defer a.Dealloc()
+
+	for i := 0; i < n; i++ {
+		a.Append(&ifaceProbe{name: fmt.Sprint(i)})
+	}
+
+	runtime.GC()
+	runtime.GC()
+
+	for i := 0; i < n; i++ {
+		assert.Equal(t, fmt.Sprint(i), a.Get(i).(*ifaceProbe).name)
+	}
+}
+
+func TestArrayInterface_Interning(t *testing.T) {
+	a := NewArrayInterface(8)
+	defer func() { a.Dealloc() }()
+
+	a.Append("a")
+	a.Append("b")
+	a.Append("a")
+	a.Append(nil)
+	a.Append([]int{1})
+	a.Append([]int{1})
+
+	assert.Equal(t, 1+4, len(a.table.values))
+	assert.Equal(t, uint32(2), a.table.refs[a.handles.Get(0)])
+
+	var values []interface{}
+	a.Values(func(v interface{}) { values = append(values, v) })
+	assert.Equal(t, []interface{}{"a", "b", nil, []int{1}, []int{1}}, values)
+
+	a.Set(0, "c")
+	a.Set(2, "c")
+	assert.Equal(t, "c", a.Get(0))
+	assert.NotContains(t, a.table.index, "a")
+
+	a.Remove(1)
+	assert.Equal(t, "c", a.Get(1))
+	assert.Equal(t, []int{1}, a.Get(3))
+	assert.NotContains(t, a.table.index, "b")
+
+	assert.Len(t, a.table.free, 2)
+	a.Append("d")
+	assert.Equal(t, 1+5, len(a.table.values), "released handles are reused")
+	assert.Len(t, a.table.free, 1)
+
+	a = a.Grow(16)
+	a.Insert(0, "e")
+	assert.Equal(t, "e", a.Get(0))
+	assert.Equal(t, "c", a.Get(1))
+
+	a = a.TrimToSize()
+	assert.Equal(t, 7, a.Cap())
+}
+
+func TestArrayInterface_NotInternable(t *testing.T) {
+	a := NewArrayInterface(8)
+	defer a.Dealloc()
+
+	type holder struct{ v interface{} }
+	a.Append(math.NaN())
+	a.Append(math.NaN())
+	a.Append(complex(1, math.NaN()))
+	a.Append(holder{[]int{1}})
+	a.Append([2]interface{}{1, map[int]int{}})
+	a.Append(holder{1.5})
+	assert.Len(t, a.table.index, 1)
+
+	assert.True(t, math.IsNaN(a.Get(0).(float64)))
+	assert.Equal(t, holder{[]int{1}}, a.Get(3))
+
+	a.RemoveRange(0, a.Len())
+	assert.Empty(t, a.table.index)
+	assert.Len(t, a.table.free, 6)
+}
@@ -0,0 +1,164 @@
+package offheap
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type point struct {
+	X, Y float64
+	Tag  uint16
+}
+
+func TestArray_Struct(t *testing.T) {
+	a := NewArray[point](4)
+	defer a.Dealloc()
+
+	a.Append(point{1, 2, 3})
+	a.Append(point{4, 5, 6})
+	a.Insert(0, point{7, 8, 9})
+
+	assert.Equal(t, 3, a.Len())
+	assert.Equal(t, point{7, 8, 9}, a.Get(0))
+	assert.Equal(t, point{1, 2, 3}, a.Get(1))
+
+	a.Swap(0, 2)
+	assert.Equal(t, point{4, 5, 6}, a.Get(0))
+	assert.Equal(t, point{7, 8, 9}, a.Get(2))
+
+	a.Remove(0)
+	assert.Equal(t, 2, a.Len())
+	assert.Equal(t, point{1, 2, 3}, a.Get(0))
+	assert.Equal(t, point{7, 8, 9}, a.Get(1))
+}
+
+func TestArray_GrowTrim(t *testing.T) {
+	a := NewArray[float32](2)
+	a.Append(1.5)
+	a.Append(2.5)
+
+	a = a.Grow(8)
+	assert.Equal(t, 8, a.Cap())
+	a.Append(3.5)
+
+	a = a.TrimToSize()
+	assert.Equal(t, 3, a.Cap())
+	assert.Equal(t, []float32{1.5, 2.5, 3.5}, a.slice)
+
+	a.Dealloc()
+}
+
+func TestArray_Empty(t *testing.T) {
+	a := NewArray[uint64](0)
+	assert.Equal(t, 0, a.Cap())
+
+	a = a.TrimToSize()
+	a.Dealloc()
+}
+
+func TestArray_TrimToEmpty(t *testing.T) {
+	a := NewArray[uint64](10)
+	a = a.TrimToSize()
+	assert.Equal(t, 0, a.Cap())
+	assert.NotPanics(t, a.Dealloc)
+}
+
+func TestArray_Values(t *testing.T) {
+	a := NewArrayUint16(8)
+	defer a.Dealloc()
+
+	for _, v := range []uint16{3, 1, 3, 2, 1} {
+		a.Append(v)
+	}
+
+	var values []uint16
+	a.Values(func(v uint16) { values = append(values, v) })
+	assert.Equal(t, []uint16{3, 1, 2}, values)
+
+	ints := NewArrayInt(4)
+	defer ints.Dealloc()
+	ints.AppendSlice([]int{-1, 7, -1, math.MaxInt})
+
+	var intValues []int
+	ints.Values(func(v int) { intValues = append(intValues, v) })
+	assert.Equal(t, []int{-1, 7, math.MaxInt}, intValues)
+
+	wide := NewArrayUint64(4)
+	defer wide.Dealloc()
+	wide.AppendSlice([]uint64{1 << 63, 2, 1 << 63})
+
+	var wideValues []uint64
+	wide.Values(func(v uint64) { wideValues = append(wideValues, v) })
+	assert.Equal(t, []uint64{1 << 63, 2}, wideValues)
+
+	type pair struct{ a, b uint8 }
+	pairs := NewArray[pair](4)
+	defer pairs.Dealloc()
+	pairs.AppendSlice([]pair{{1, 2}, {2, 1}, {1, 2}})
+
+	var pairValues []pair
+	pairs.Values(func(v pair) { pairValues = append(pairValues, v) })
+	assert.Equal(t, []pair{{1, 2}, {2, 1}}, pairValues)
+}
+
+func TestNewArray_Pointers(t *testing.T) {
+	assert.Panics(t, func() { NewArray[*int](1) })
+	assert.Panics(t, func() { NewArray[string](1) })
+	assert.Panics(t, func() { NewArray[struct{ b []byte }](1) })
+}
+
+func TestArray_GrowPreservesData(t *testing.T) {
+	n := 1 << 20
+
+	a := NewArrayUint64(n)
+	for i := 0; i < n; i++ {
+		a.Append(uint64(i))
+	}
+
+	grown := a.Grow(4 * n)
+	assert.Equal(t, n, grown.Len())
+
+	grown.Append(uint64(n))
+	trimmed := grown.TrimToSize()
+	assert.Equal(t, n+1, trimmed.Cap())
+
+	for i := 0; i <= n; i++ {
+		if trimmed.Get(i) != uint64(i) {
+			t.Fatalf("unexpected value at %d: %d", i, trimmed.Get(i))
+		}
+	}
+
+	trimmed.Dealloc()
+}
+
+func BenchmarkArray_Grow(b *testing.B) {
+	a := NewArrayUint64(1 << 16)
+	for i := 0; i < a.Cap(); i++ {
+		a.Append(uint64(i))
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		a = a.Grow(a.Cap() + 1<<16)
+	}
+
+	a.Dealloc()
+}
+
+func TestArray_GrowAfterOverflow(t *testing.T) {
+	if checked {
+		t.Skip("appending beyond capacity panics in checked mode")
+	}
+
+	a := NewArrayUint16(2)
+	for i := uint16(1); i <= 3; i++ {
+		a.Append(i)
+	}
+
+	a = a.TrimToSize()
+	assert.Equal(t, []uint16{1, 2, 3}, a.slice)
+	a.Dealloc()
+}
@@ -0,0 +1,49 @@
+package offheap
+
+// AppendSlice adds all elements of vs to the end, growing the array like Append does.
+func (o *Array[T]) AppendSlice(vs []T) {
+	o.checkMutable()
+	o.autoGrow(len(vs))
+	o.checkCapacity(len(o.slice), len(vs))
+
+	o.slice = append(o.slice, vs...)
+}
+
+// InsertSlice inserts all elements of vs at index i, shifting the following elements to the right.
+func (o *Array[T]) InsertSlice(i int, vs []T) {
+	o.checkMutable()
+	o.autoGrow(len(vs))
+	o.checkCapacity(len(o.slice), len(vs))
+
+	n := len(o.slice)
+	o.slice = append(o.slice, vs...)
+	copy(o.slice[i+len(vs):], o.slice[i:n])
+	copy(o.slice[i:], vs)
+}
+
+// CopyFrom overwrites elements starting at index i with elements [from, to) of other.
+// Source and destination may overlap, including other being the same array.
+func (o *Array[T]) CopyFrom(i int, other *Array[T], from, to int) {
+	o.checkMutable()
+	other.checkLive()
+
+	copy(o.slice[i:i+to-from], other.slice[from:to])
+}
+
+// Fill sets elements [from, to) to v.
+func (o *Array[T]) Fill(from, to int, v T) {
+	o.checkMutable()
+
+	slice := o.slice[from:to]
+	for i := range slice {
+		slice[i] = v
+	}
+}
+
+// RemoveRange removes elements [from, to), preserving the order of remaining ones.
+// It is a caller's responsibility to call TrimToSize() for reclaiming space.
+func (o *Array[T]) RemoveRange(from, to int) {
+	o.checkMutable()
+
+	o.slice = append(o.slice[:from], o.slice[to:]...)
+}
@@ -0,0 +1,53 @@
+package offheap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArray_BulkOps(t *testing.T) {
+	a := NewArrayUint64(16)
+	defer func() { a.Dealloc() }()
+
+	a.AppendSlice([]uint64{1, 2, 3, 4, 5})
+	a.InsertSlice(1, []uint64{10, 11})
+	assert.Equal(t, []uint64{1, 10, 11, 2, 3, 4, 5}, a.slice)
+
+	a.CopyFrom(0, a, 3, 7)
+	assert.Equal(t, []uint64{2, 3, 4, 5, 3, 4, 5}, a.slice)
+
+	a.Fill(4, 7, 0)
+	assert.Equal(t, []uint64{2, 3, 4, 5, 0, 0, 0}, a.slice)
+
+	a.RemoveRange(1, 3)
+	assert.Equal(t, []uint64{2, 5, 0, 0, 0}, a.slice)
+}
+
+func TestArrayInterface_BulkOps(t *testing.T) {
+	a := NewArrayInterface(16)
+	defer func() { a.Dealloc() }()
+
+	a.AppendSlice([]interface{}{"a", "b", "c", "d"})
+	a.InsertSlice(0, []interface{}{"x"})
+
+	a.CopyFrom(0, a, 2, 5)
+	assert.Equal(t, []interface{}{"b", "c", "d", "c", "d"}, values(a))
+
+	a.RemoveRange(3, 5)
+	assert.Equal(t, []interface{}{"b", "c", "d"}, values(a))
+
+	a.Fill(0, 2, nil)
+	assert.Equal(t, []interface{}{nil, nil, "d"}, values(a))
+
+	// Only "d" is still referenced
+	assert.Len(t, a.table.index, 1)
+	assert.Equal(t, uint32(1), a.table.refs[a.handles.Get(2)])
+}
+
+func values(a *ArrayInterface) (vs []interface{}) {
+	for i := 0; i < a.Len(); i++ {
+		vs = append(vs, a.Get(i))
+	}
+	return
+}
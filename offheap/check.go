@@ -0,0 +1,56 @@
+package offheap
+
+import "fmt"
+
+// state of an array in regard to the memory it maps
+type state uint8
+
+const (
+	live        state = iota
+	deallocated       // Released by Dealloc or Close
+	moved             // Mapping was handed over to the array returned by Grow or TrimToSize
+)
+
+// checkLive panics if the array no longer owns its mapping. Compiled in with `offheapcheck' build tag only.
+func (o *array) checkLive() {
+	if !checked || o.state == live {
+		return
+	}
+
+	if o.state == moved {
+		panic(o.checkError("use of %s array replaced by Grow or TrimToSize", o.typeDesc()))
+	}
+	panic(o.checkError("use of %s array after Dealloc", o.typeDesc()))
+}
+
+// checkCapacity panics if appending n elements to len ones would exceed mapped capacity,
+// which makes append silently move data to Go heap. Compiled in with `offheapcheck' build tag only.
+func (o *array) checkCapacity(len, n int) {
+	if checked && len+n > o.cap {
+		panic(o.checkError("append beyond capacity %d of %s array, Grow() it first", o.cap, o.typeDesc()))
+	}
+}
+
+// checkDealloc panics on repeated Dealloc. Compiled in with `offheapcheck' build tag only.
+func (o *array) checkDealloc() {
+	if !checked || o.state == live {
+		return
+	}
+
+	if o.state == moved {
+		panic(o.checkError("Dealloc of %s array replaced by Grow or TrimToSize", o.typeDesc()))
+	}
+	panic(o.checkError("double Dealloc of %s array", o.typeDesc()))
+}
+
+func (o *array) typeDesc() string {
+	return "[]" + o.typ
+}
+
+func (o *array) checkError(format string, args ...interface{}) error {
+	err := fmt.Errorf("offheap: "+format, args...)
+	if o.stack != nil {
+		err = fmt.Errorf("%w, allocated at:\n%s", err, formatStack(o.stack))
+	}
+	return err
+}
@@ -0,0 +1,6 @@
+//go:build offheapcheck
+
+package offheap
+
+// checked enables detection of use after Dealloc, double Dealloc and appending beyond capacity
+const checked = true
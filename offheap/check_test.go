@@ -0,0 +1,55 @@
+//go:build offheapcheck
+
+package offheap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecked_UseAfterDealloc(t *testing.T) {
+	a := NewArrayUint32(2)
+	a.Append(1)
+	a.Dealloc()
+
+	assert.PanicsWithError(t, "offheap: use of []uint32 array after Dealloc", func() { a.Get(0) })
+	assert.PanicsWithError(t, "offheap: use of []uint32 array after Dealloc", func() { a.Len() })
+	assert.PanicsWithError(t, "offheap: double Dealloc of []uint32 array", a.Dealloc)
+}
+
+func TestChecked_UseAfterGrow(t *testing.T) {
+	a := NewArrayUint32(2)
+	b := a.Grow(4)
+	defer b.Dealloc()
+
+	assert.PanicsWithError(t, "offheap: use of []uint32 array replaced by Grow or TrimToSize", func() { a.Append(1) })
+	assert.PanicsWithError(t, "offheap: Dealloc of []uint32 array replaced by Grow or TrimToSize", a.Dealloc)
+}
+
+func TestChecked_AppendBeyondCapacity(t *testing.T) {
+	a := NewArrayUint32(1)
+	defer a.Dealloc()
+
+	a.Append(1)
+	assert.PanicsWithError(t, "offheap: append beyond capacity 1 of []uint32 array, Grow() it first", func() { a.Append(2) })
+	assert.PanicsWithError(t, "offheap: append beyond capacity 1 of []uint32 array, Grow() it first", func() { a.Insert(0, 2) })
+	assert.Equal(t, 1, a.Len())
+}
+
+func TestChecked_AllocationStack(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	a := NewArrayUint32(1)
+	a.Dealloc()
+
+	defer func() {
+		err, _ := recover().(error)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "offheap: double Dealloc of []uint32 array, allocated at:")
+		assert.Contains(t, err.Error(), "TestChecked_AllocationStack")
+	}()
+
+	a.Dealloc()
+}
@@ -0,0 +1,26 @@
+package offheap
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// MapError reports a failed memory mapping operation. It wraps the underlying syscall.Errno,
+// so errors.Is(err, syscall.ENOMEM) can be used to detect memory pressure.
+type MapError struct {
+	Op   string  // System call, e.g. "mmap" or "munmap"
+	Size uintptr // Size of the mapping in bytes
+	Err  error
+}
+
+func (e *MapError) Error() string {
+	return fmt.Sprintf("offheap: %s of %d bytes: %v", e.Op, e.Size, e.Err)
+}
+
+func (e *MapError) Unwrap() error {
+	return e.Err
+}
+
+func mapError(op string, size uintptr, errno syscall.Errno) error {
+	return &MapError{Op: op, Size: size, Err: errno}
+}
@@ -0,0 +1,55 @@
+package offheap
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hugeSize is a number of elements too large to be mapped on 64-bit platforms
+const hugeSize = math.MaxInt >> 5
+
+func TestNewArrayE(t *testing.T) {
+	_, err := NewArrayUint16E(-1)
+	assert.ErrorIs(t, err, syscall.EINVAL)
+
+	if strconv.IntSize == 32 {
+		t.Skip("huge arrays may fit in 32-bit address space")
+	}
+
+	_, err = NewArrayUint64E(hugeSize)
+	assert.ErrorIs(t, err, syscall.ENOMEM)
+
+	var mapErr *MapError
+	assert.True(t, errors.As(err, &mapErr))
+	assert.Equal(t, "mmap", mapErr.Op)
+
+	assert.Panics(t, func() { NewArrayUint64(hugeSize) })
+}
+
+func TestArray_GrowE(t *testing.T) {
+	a := NewArrayUint32(2)
+	a.Append(7)
+
+	if strconv.IntSize == 64 {
+		_, err := a.GrowE(math.MaxInt >> 21) // Too large to remap, but not an invalid size
+		assert.ErrorIs(t, err, syscall.ENOMEM)
+
+		// Array is still usable
+		assert.Equal(t, uint32(7), a.Get(0))
+	}
+
+	a, err := a.GrowE(4)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, a.Cap())
+
+	a, err = a.TrimToSizeE()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, a.Cap())
+
+	a.Dealloc()
+}
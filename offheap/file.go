@@ -0,0 +1,144 @@
+package offheap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// FileMode defines how a file-backed array is mapped
+type FileMode int
+
+const (
+	// ReadOnly maps an existing file for reading. The array is frozen, so mutating methods panic with ErrFrozen.
+	ReadOnly FileMode = iota
+
+	// ReadWrite maps a file for reading and writing, creating it if needed.
+	// Changes are written through to the file, which is extended as elements are appended.
+	ReadWrite
+)
+
+// OpenArrayFile maps a file holding raw T elements in native byte order, as written by a ReadWrite array.
+//
+// The file is mapped shared, so the array is immediately populated from the page cache without parsing.
+// Array length is initially the number of elements in the file; a ReadWrite array grows the file
+// on Append or Grow and is flushed and truncated to its length on Close.
+func OpenArrayFile[T any](path string, mode FileMode) (*Array[T], error) {
+	return OpenArrayFileWithOptions[T](path, mode, Options{})
+}
+
+// OpenArrayFileWithOptions is like OpenArrayFile, with the file mapped according to opts.
+func OpenArrayFileWithOptions[T any](path string, mode FileMode, opts Options) (*Array[T], error) {
+	if err := checkElem[T](); err != nil {
+		return nil, err
+	}
+
+	flag, prot := os.O_RDONLY, syscall.PROT_READ
+	if mode == ReadWrite {
+		flag, prot = os.O_RDWR|os.O_CREATE, syscall.PROT_READ|syscall.PROT_WRITE
+	}
+
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	var kEl T
+
+	base := array{sz: unsafe.Sizeof(kEl), file: f, prot: prot, opts: opts, frozen: mode == ReadOnly, typ: typeName[T]()}
+	if info.Size()%int64(base.sz) != 0 {
+		_ = f.Close()
+		return nil, fmt.Errorf("offheap: %s: size %d is not a multiple of element size %d", path, info.Size(), base.sz)
+	}
+
+	size := int(info.Size() / int64(base.sz))
+	if err := base.mapFile(size); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return track(&Array[T]{
+		array: base,
+		slice: unsafe.Slice((*T)(base.data), size),
+	}), nil
+}
+
+// Flush writes changes of a file-backed array through to the file.
+func (o *Array[T]) Flush() error {
+	o.checkLive()
+
+	if o.file == nil || o.prot&syscall.PROT_WRITE == 0 || o.data == nil {
+		return nil
+	}
+
+	return msync(o.data, o.size())
+}
+
+func (o *array) mapFile(len int) (err error) {
+	o.cap = len
+	if len == 0 {
+		o.data = nil // Copies of a mapped array keep its pointer
+		return nil
+	}
+
+	o.data, err = mmap(o.size(), o.prot, syscall.MAP_SHARED|o.mapFlags(), int(o.file.Fd()), o.offset)
+	if err != nil {
+		return err
+	}
+
+	account(o.typ, 1, int64(o.size()))
+
+	if err = o.advise(0); err != nil {
+		_ = o.deallocSlice()
+	}
+	return
+}
+
+// extendFile makes sure the file is large enough to be mapped with capacity for size elements.
+// A read-only file cannot be extended, so mapping past its end fails with ErrFrozen.
+func (o *array) extendFile(size int) error {
+	if size <= o.cap {
+		return nil
+	}
+
+	if o.prot&syscall.PROT_WRITE == 0 {
+		return ErrFrozen
+	}
+
+	return o.file.Truncate(o.offset + int64(size)*int64(o.sz))
+}
+
+func (o *Array[T]) closeFile() (err error) {
+	// Elements appended past capacity are on Go heap, move them to the file before truncating it to length
+	if len(o.slice) > o.cap && o.prot&syscall.PROT_WRITE != 0 {
+		err = o.resizeInPlace(len(o.slice))
+	}
+
+	if flushErr := o.Flush(); err == nil {
+		err = flushErr
+	}
+	if unmapErr := o.deallocSlice(); err == nil {
+		err = unmapErr
+	}
+
+	if o.prot&syscall.PROT_WRITE != 0 {
+		if truncErr := o.file.Truncate(int64(len(o.slice)) * int64(o.sz)); err == nil {
+			err = truncErr
+		}
+	}
+
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	o.slice = nil
+	o.file = nil
+	return err
+}
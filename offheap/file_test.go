@@ -0,0 +1,110 @@
+package offheap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenArrayFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+
+	a, err := OpenArrayUint64File(path, ReadWrite)
+	require.NoError(t, err)
+	assert.Equal(t, 0, a.Len())
+
+	a = a.Grow(4)
+	for i := uint64(1); i <= 3; i++ {
+		a.Append(i * 10)
+	}
+	require.NoError(t, a.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3*8), info.Size())
+
+	ro, err := OpenArrayUint64File(path, ReadOnly)
+	require.NoError(t, err)
+	assert.Equal(t, 3, ro.Len())
+	assert.Equal(t, uint64(20), ro.Get(1))
+	require.NoError(t, ro.Close())
+
+	rw, err := OpenArrayUint64File(path, ReadWrite)
+	require.NoError(t, err)
+	rw.Set(0, 5)
+	rw = rw.Grow(8)
+	rw.Append(40)
+	rw = rw.TrimToSize()
+	require.NoError(t, rw.Flush())
+	rw.Dealloc()
+
+	ro, err = OpenArrayUint64File(path, ReadOnly)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{5, 20, 30, 40}, ro.slice)
+	ro.Dealloc()
+}
+
+func TestOpenArrayFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := OpenArrayUint64File(filepath.Join(dir, "missing"), ReadOnly)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	odd := filepath.Join(dir, "odd")
+	require.NoError(t, os.WriteFile(odd, []byte{1, 2, 3}, 0o644))
+	_, err = OpenArrayUint32File(odd, ReadOnly)
+	assert.Error(t, err)
+
+	_, err = OpenArrayFile[*int](odd, ReadOnly)
+	assert.Error(t, err)
+}
+
+func TestOpenArrayFile_ReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, make([]byte, 16), 0o644))
+
+	ro, err := OpenArrayUint64File(path, ReadOnly)
+	require.NoError(t, err)
+	defer ro.Dealloc()
+
+	assert.True(t, ro.Frozen())
+	assert.PanicsWithValue(t, ErrFrozen, func() { ro.Set(0, 5) })
+	assert.PanicsWithValue(t, ErrFrozen, func() { ro.Append(5) })
+	_, err = ro.GrowE(4)
+	assert.ErrorIs(t, err, ErrFrozen)
+	assert.ErrorIs(t, ro.extendFile(4), ErrFrozen)
+	assert.Equal(t, uint64(0), ro.Get(1))
+}
+
+func TestOpenArrayFile_AppendPastCapacity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+
+	rw, err := OpenArrayUint64File(path, ReadWrite)
+	require.NoError(t, err)
+	rw.Append(42)
+	rw.AppendSlice([]uint64{43, 44})
+	require.NoError(t, rw.Close())
+
+	ro, err := OpenArrayUint64File(path, ReadOnly)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{42, 43, 44}, ro.slice)
+	ro.Dealloc()
+}
+
+func TestOpenArrayFile_TrimToEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+
+	rw, err := OpenArrayUint64File(path, ReadWrite)
+	require.NoError(t, err)
+	rw = rw.Grow(4)
+	rw = rw.TrimToSize()
+	assert.Equal(t, 0, rw.Cap())
+	require.NoError(t, rw.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
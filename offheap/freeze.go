@@ -0,0 +1,40 @@
+package offheap
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ErrFrozen is returned, or raised as a panic by methods without an error result, on attempt to modify a frozen array
+var ErrFrozen = errors.New("offheap: array is frozen")
+
+// Freeze makes the array read-only. Memory is protected with mprotect, so a write bypassing the array API
+// faults instead of silently corrupting data, and mutating methods panic with ErrFrozen.
+func (o *array) Freeze() error {
+	o.checkLive()
+
+	if o.frozen {
+		return nil
+	}
+
+	if o.data != nil {
+		if err := mprotect(o.data, o.size(), syscall.PROT_READ); err != nil {
+			return err
+		}
+	}
+
+	o.frozen = true
+	return nil
+}
+
+func (o *array) Frozen() bool {
+	return o.frozen
+}
+
+func (o *array) checkMutable() {
+	o.checkLive()
+
+	if o.frozen {
+		panic(ErrFrozen)
+	}
+}
@@ -0,0 +1,35 @@
+package offheap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArray_Freeze(t *testing.T) {
+	a := NewArrayUint64(4)
+	defer a.Dealloc()
+
+	a.Append(1)
+	a.Append(2)
+
+	assert.NoError(t, a.Freeze())
+	assert.True(t, a.Frozen())
+	assert.NoError(t, a.Freeze())
+
+	assert.Equal(t, uint64(2), a.Get(1))
+
+	assert.PanicsWithValue(t, ErrFrozen, func() { a.Set(0, 3) })
+	assert.PanicsWithValue(t, ErrFrozen, func() { a.Append(3) })
+	assert.PanicsWithValue(t, ErrFrozen, func() { a.Insert(0, 3) })
+	assert.PanicsWithValue(t, ErrFrozen, func() { a.Swap(0, 1) })
+	assert.PanicsWithValue(t, ErrFrozen, func() { a.Remove(0) })
+
+	_, err := a.GrowE(8)
+	assert.ErrorIs(t, err, ErrFrozen)
+
+	_, err = a.TrimToSizeE()
+	assert.ErrorIs(t, err, ErrFrozen)
+
+	assert.Equal(t, []uint64{1, 2}, a.slice)
+}
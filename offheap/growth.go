@@ -0,0 +1,75 @@
+package offheap
+
+// GrowthPolicy defines how an array is grown when appending to it while full.
+// Zero value disables automatic growth.
+type GrowthPolicy struct {
+	Factor  float64 // Multiplier applied to current capacity
+	MinStep int     // Minimal number of elements to grow by
+	MaxStep int     // Maximal number of elements to grow by, unlimited if zero
+}
+
+// DefaultGrowthPolicy grows arrays by 25%, but at least by a page worth of 64-bit elements
+var DefaultGrowthPolicy = GrowthPolicy{Factor: 1.25, MinStep: 512}
+
+func (p GrowthPolicy) enabled() bool {
+	return p.Factor > 1 || p.MinStep > 0
+}
+
+// next returns capacity to grow to from cap, which is at least required
+func (p GrowthPolicy) next(cap, required int) int {
+	step := int(float64(cap)*p.Factor) - cap
+	if step < p.MinStep {
+		step = p.MinStep
+	}
+	if p.MaxStep > 0 && step > p.MaxStep {
+		step = p.MaxStep
+	}
+
+	if size := cap + step; size > required {
+		return size
+	}
+	return required
+}
+
+// Reserve makes sure at least n more elements can be added without exceeding capacity.
+// Array is grown in place according to Options.Growth, or to exactly fit n more elements if growth is disabled.
+func (o *Array[T]) Reserve(n int) error {
+	o.checkLive()
+
+	required := len(o.slice) + n
+	if required <= o.cap {
+		return nil
+	}
+
+	if o.frozen {
+		return ErrFrozen
+	}
+
+	size := required
+	if o.opts.Growth.enabled() {
+		size = o.opts.Growth.next(o.cap, required)
+	}
+
+	return o.resizeInPlace(size)
+}
+
+// autoGrow makes room for n more elements if automatic growth is enabled.
+// Writable file-backed arrays always grow, by DefaultGrowthPolicy unless configured otherwise,
+// so that appended elements are written to the file.
+func (o *Array[T]) autoGrow(n int) {
+	if len(o.slice)+n <= o.cap {
+		return
+	}
+
+	policy := o.opts.Growth
+	if !policy.enabled() {
+		if o.file == nil {
+			return
+		}
+		policy = DefaultGrowthPolicy
+	}
+
+	if err := o.resizeInPlace(policy.next(o.cap, len(o.slice)+n)); err != nil {
+		panic(err)
+	}
+}
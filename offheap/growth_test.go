@@ -0,0 +1,49 @@
+package offheap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrowthPolicy_Next(t *testing.T) {
+	p := GrowthPolicy{Factor: 2, MinStep: 10, MaxStep: 100}
+
+	assert.Equal(t, 10, p.next(0, 1))
+	assert.Equal(t, 40, p.next(20, 21))
+	assert.Equal(t, 300, p.next(200, 201))
+	assert.Equal(t, 500, p.next(200, 500))
+}
+
+func TestArray_AppendGrows(t *testing.T) {
+	a, err := NewArrayUint64WithOptions(1, Options{Growth: DefaultGrowthPolicy})
+	require.NoError(t, err)
+	defer func() { a.Dealloc() }()
+
+	for i := 0; i < 10_000; i++ {
+		a.Append(uint64(i))
+	}
+
+	assert.Equal(t, 10_000, a.Len())
+	assert.LessOrEqual(t, a.Len(), a.Cap())
+	for i := 0; i < 10_000; i++ {
+		assert.Equal(t, uint64(i), a.Get(i))
+	}
+}
+
+func TestArray_Reserve(t *testing.T) {
+	a := NewArrayUint64(4)
+	defer func() { a.Dealloc() }()
+
+	require.NoError(t, a.Reserve(4))
+	assert.Equal(t, 4, a.Cap())
+
+	a.Append(1)
+	require.NoError(t, a.Reserve(10))
+	assert.Equal(t, 11, a.Cap())
+	assert.Equal(t, uint64(1), a.Get(0))
+
+	require.NoError(t, a.Freeze())
+	assert.ErrorIs(t, a.Reserve(100), ErrFrozen)
+}
@@ -0,0 +1,93 @@
+package offheap
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func mmap(length uintptr, prot, flags, fd int, offset int64) (unsafe.Pointer, error) {
+	data, _, errno := syscall.Syscall6(
+		syscall.SYS_MMAP,
+		0,
+		length,
+		uintptr(prot),
+		uintptr(flags),
+		uintptr(fd),
+		uintptr(offset),
+	)
+	if errno != 0 {
+		return nil, mapError("mmap", length, errno)
+	}
+
+	return *(*unsafe.Pointer)(unsafe.Pointer(&data)), nil
+}
+
+func munmap(addr unsafe.Pointer, length uintptr) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MUNMAP,
+		uintptr(addr),
+		length,
+		0,
+	)
+	if errno != 0 {
+		return mapError("munmap", length, errno)
+	}
+
+	return nil
+}
+
+func msync(addr unsafe.Pointer, length uintptr) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MSYNC,
+		uintptr(addr),
+		length,
+		syscall.MS_SYNC,
+	)
+	if errno != 0 {
+		return mapError("msync", length, errno)
+	}
+
+	return nil
+}
+
+func madvise(addr unsafe.Pointer, length uintptr, advice int) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MADVISE,
+		uintptr(addr),
+		length,
+		uintptr(advice),
+	)
+	if errno != 0 {
+		return mapError("madvise", length, errno)
+	}
+
+	return nil
+}
+
+func mlock(addr unsafe.Pointer, length uintptr) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MLOCK,
+		uintptr(addr),
+		length,
+		0,
+	)
+	if errno != 0 {
+		return mapError("mlock", length, errno)
+	}
+
+	return nil
+}
+
+func mprotect(addr unsafe.Pointer, length uintptr, prot int) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MPROTECT,
+		uintptr(addr),
+		length,
+		uintptr(prot),
+	)
+	if errno != 0 {
+		return mapError("mprotect", length, errno)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package offheap
+
+import (
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// Options tune how memory of an off-heap array is mapped. The zero value keeps kernel defaults.
+// Options are retained by the array and applied again whenever it is grown or trimmed.
+type Options struct {
+	HugePages bool   // Advise transparent huge pages to reduce TLB misses, anonymous memory on Linux only
+	Access    Access // Expected access pattern
+	Populate  bool   // Prefault pages on allocation rather than on first access
+	Lock      bool   // Lock pages in memory with mlock, preventing them from being swapped out
+
+	Growth GrowthPolicy // Grow automatically when appending to a full array, unless zero
+}
+
+// Access is an expected memory access pattern, passed to the kernel with madvise
+type Access int
+
+const (
+	AccessNormal     Access = iota
+	AccessRandom            // Random lookups, disables read-ahead
+	AccessSequential        // Sequential scans, enables aggressive read-ahead
+)
+
+func (o array) Options() Options {
+	return o.opts
+}
+
+func (o array) mapFlags() int {
+	if o.opts.Populate {
+		return mapPopulate
+	}
+	return 0
+}
+
+// advise applies options to the mapping; pages from offset on are prefaulted if needed
+func (o *array) advise(offset uintptr) error {
+	if o.data == nil {
+		return nil
+	}
+
+	size := o.size()
+
+	switch o.opts.Access {
+	case AccessRandom:
+		if err := madvise(o.data, size, syscall.MADV_RANDOM); err != nil {
+			return err
+		}
+	case AccessSequential:
+		if err := madvise(o.data, size, syscall.MADV_SEQUENTIAL); err != nil {
+			return err
+		}
+	}
+
+	if o.opts.HugePages && madvHugePage != 0 && o.file == nil {
+		if err := madvise(o.data, size, madvHugePage); err != nil {
+			return err
+		}
+	}
+
+	// MAP_POPULATE only applies to freshly mapped memory
+	if o.opts.Populate && (mapPopulate == 0 || offset > 0) {
+		o.prefault(offset)
+	}
+
+	if o.opts.Lock {
+		return mlock(o.data, size)
+	}
+
+	return nil
+}
+
+// prefault touches every page from offset on
+func (o *array) prefault(offset uintptr) {
+	pageSize := uintptr(syscall.Getpagesize())
+	writable := o.prot&syscall.PROT_WRITE != 0 || o.file == nil
+
+	for p := offset &^ (pageSize - 1); p < o.size(); p += pageSize {
+		word := (*uint32)(unsafe.Add(o.data, p))
+		if writable {
+			atomic.AddUint32(word, 0)
+		} else {
+			_ = atomic.LoadUint32(word)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package offheap
+
+import "syscall"
+
+const (
+	mapPopulate  = syscall.MAP_POPULATE
+	madvHugePage = syscall.MADV_HUGEPAGE
+)
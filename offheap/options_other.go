@@ -0,0 +1,9 @@
+//go:build !linux
+
+package offheap
+
+// Neither prefaulting on mmap nor transparent huge pages are available
+const (
+	mapPopulate  = 0
+	madvHugePage = 0
+)
@@ -0,0 +1,36 @@
+package offheap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewArrayWithOptions(t *testing.T) {
+	opts := Options{
+		HugePages: true,
+		Access:    AccessRandom,
+		Populate:  true,
+		Lock:      true,
+	}
+
+	a, err := NewArrayUint64WithOptions(512, opts)
+	require.NoError(t, err)
+	assert.Equal(t, opts, a.Options())
+
+	for i := 0; i < 512; i++ {
+		a.Append(uint64(i))
+	}
+
+	a, err = a.GrowE(1024)
+	require.NoError(t, err)
+	assert.Equal(t, opts, a.Options())
+	assert.Equal(t, uint64(511), a.Get(511))
+
+	a, err = a.TrimToSizeE()
+	require.NoError(t, err)
+	assert.Equal(t, opts, a.Options())
+
+	a.Dealloc()
+}
@@ -0,0 +1,401 @@
+package offheap
+
+import (
+	"fmt"
+	"math/bits"
+	"syscall"
+)
+
+const wordBits = 64
+
+// PackedArray is an off-heap array of unsigned integers of a fixed bit width, packed into 64-bit words.
+// An element may span two adjacent words. Options.Growth applies to the number of backing words.
+type PackedArray struct {
+	words *Array[uint64]
+	width uint
+	len   int
+}
+
+// NewPackedArray allocates an off-heap array with capacity for size elements of width bits each.
+// Panics if width is not in range [1, 64].
+func NewPackedArray(width uint, size int) *PackedArray {
+	return must(NewPackedArrayE(width, size))
+}
+
+// NewPackedArrayE is like NewPackedArray, but returns an error instead of panicking.
+func NewPackedArrayE(width uint, size int) (*PackedArray, error) {
+	return NewPackedArrayWithOptions(width, size, Options{})
+}
+
+// NewPackedArrayWithOptions is like NewPackedArrayE, with the array mapped according to opts.
+func NewPackedArrayWithOptions(width uint, size int, opts Options) (*PackedArray, error) {
+	if width < 1 || width > wordBits {
+		return nil, fmt.Errorf("offheap: packed width %d is not in range [1, %d]", width, wordBits)
+	}
+	if size < 0 {
+		return nil, mapError("mmap", 0, syscall.EINVAL)
+	}
+
+	words, err := newArray[uint64](packedType, wordsFor(width, size), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PackedArray{words: words, width: width}, nil
+}
+
+// wordsFor returns the number of words holding n elements of width bits
+func wordsFor(width uint, n int) int {
+	return int((uint64(n)*uint64(width) + wordBits - 1) / wordBits)
+}
+
+// Width returns the number of bits per element
+func (o *PackedArray) Width() uint {
+	return o.width
+}
+
+// Max returns the largest value which fits in an element
+func (o *PackedArray) Max() uint64 {
+	return ^uint64(0) >> (wordBits - o.width)
+}
+
+func (o *PackedArray) Len() int {
+	o.words.checkLive()
+
+	return o.len
+}
+
+func (o *PackedArray) Cap() int {
+	return int(uint64(o.words.Cap()) * wordBits / uint64(o.width))
+}
+
+func (o *PackedArray) Options() Options {
+	return o.words.Options()
+}
+
+func (o *PackedArray) Dealloc() {
+	o.words.Dealloc()
+}
+
+func (o *PackedArray) Close() error {
+	return o.words.Close()
+}
+
+func (o *PackedArray) Freeze() error {
+	return o.words.Freeze()
+}
+
+func (o *PackedArray) Frozen() bool {
+	return o.words.Frozen()
+}
+
+func (o *PackedArray) Get(i int) uint64 {
+	o.checkIndex(i, o.Len())
+
+	return o.get(i)
+}
+
+// Set stores val at index i. Panics if val does not fit in Width bits.
+func (o *PackedArray) Set(i int, val uint64) {
+	o.words.checkMutable()
+	o.checkIndex(i, o.len)
+	o.checkFits(val)
+
+	o.set(i, val)
+}
+
+func (o *PackedArray) Swap(i, j int) {
+	o.words.checkMutable()
+	o.checkIndex(i, o.len)
+	o.checkIndex(j, o.len)
+
+	vi, vj := o.get(i), o.get(j)
+	o.set(i, vj)
+	o.set(j, vi)
+}
+
+// Insert inserts v at index i, shifting the following elements to the right.
+func (o *PackedArray) Insert(i int, v uint64) {
+	o.InsertSlice(i, []uint64{v})
+}
+
+// InsertSlice inserts all elements of vs at index i, shifting the following elements to the right.
+func (o *PackedArray) InsertSlice(i int, vs []uint64) {
+	o.words.checkMutable()
+	o.checkIndex(i, o.len+1)
+	for _, v := range vs {
+		o.checkFits(v)
+	}
+
+	n := o.len
+	o.extend(len(vs))
+	w := uint64(o.width)
+	moveBitsUp(o.words.slice, uint64(i)*w, uint64(n)*w, uint64(len(vs))*w)
+	for k, v := range vs {
+		o.set(i+k, v)
+	}
+}
+
+// Append add an element to the end, see Array.Append. Panics if v does not fit in Width bits.
+func (o *PackedArray) Append(v uint64) {
+	o.words.checkMutable()
+	o.checkFits(v)
+
+	o.extend(1)
+	o.set(o.len-1, v)
+}
+
+// Extend adds n zero elements to the end, see Array.Append
+func (o *PackedArray) Extend(n int) {
+	o.words.checkMutable()
+	if n < 0 {
+		panic(fmt.Sprintf("offheap: cannot extend by %d elements", n))
+	}
+
+	// Bits of removed elements may be left past the end of the last word in use
+	if end := uint64(o.len) * uint64(o.width) % wordBits; end != 0 {
+		last := o.words.Len() - 1
+		o.words.Set(last, o.words.Get(last)&(1<<end-1))
+	}
+
+	need := wordsFor(o.width, o.len+n) - o.words.Len()
+	for ; need > len(zeroWords); need -= len(zeroWords) {
+		o.words.AppendSlice(zeroWords[:])
+	}
+	o.words.AppendSlice(zeroWords[:need])
+	o.len += n
+}
+
+// zeroWords are appended to extend arrays with zero elements
+var zeroWords [512]uint64
+
+// AppendSlice adds all elements of vs to the end, see Array.AppendSlice
+func (o *PackedArray) AppendSlice(vs []uint64) {
+	o.InsertSlice(o.Len(), vs)
+}
+
+// CopyFrom overwrites elements starting at index i with elements [from, to) of other, see Array.CopyFrom.
+// Panics if a copied value does not fit in Width bits.
+func (o *PackedArray) CopyFrom(i int, other *PackedArray, from, to int) {
+	o.words.checkMutable()
+	other.checkRange(from, to, other.Len())
+	o.checkRange(i, i+to-from, o.len)
+
+	copyOne := func(k int) {
+		v := other.get(from + k)
+		if other.width > o.width {
+			o.checkFits(v)
+		}
+		o.set(i+k, v)
+	}
+
+	if other == o && i > from {
+		for k := to - from - 1; k >= 0; k-- {
+			copyOne(k)
+		}
+	} else {
+		for k := 0; k < to-from; k++ {
+			copyOne(k)
+		}
+	}
+}
+
+// Fill sets elements [from, to) to v
+func (o *PackedArray) Fill(from, to int, v uint64) {
+	o.words.checkMutable()
+	o.checkRange(from, to, o.len)
+	o.checkFits(v)
+
+	for i := from; i < to; i++ {
+		o.set(i, v)
+	}
+}
+
+// Remove removes an element at index, preserving the order of remaining ones, see Array.Remove
+func (o *PackedArray) Remove(i int) {
+	o.RemoveRange(i, i+1)
+}
+
+// RemoveRange removes elements [from, to), preserving the order of remaining ones, see Array.RemoveRange
+func (o *PackedArray) RemoveRange(from, to int) {
+	o.words.checkMutable()
+	o.checkRange(from, to, o.len)
+
+	for k := to; k < o.len; k++ {
+		o.set(from+k-to, o.get(k))
+	}
+	o.truncate(o.len - (to - from))
+}
+
+// Reserve makes sure at least n more elements can be added without exceeding capacity, see Array.Reserve
+func (o *PackedArray) Reserve(n int) error {
+	return o.words.Reserve(wordsFor(o.width, o.Len()+n) - o.words.Len())
+}
+
+func (o *PackedArray) Grow(size int) *PackedArray {
+	return must(o.GrowE(size))
+}
+
+// GrowE is like Grow, but returns an error instead of panicking. On failure the array is left intact.
+func (o *PackedArray) GrowE(size int) (*PackedArray, error) {
+	words, err := o.words.GrowE(wordsFor(o.width, size))
+	if err != nil {
+		return nil, err
+	}
+	return o.moveTo(words), nil
+}
+
+func (o *PackedArray) TrimToSize() *PackedArray {
+	return must(o.TrimToSizeE())
+}
+
+// TrimToSizeE is like TrimToSize, but returns an error instead of panicking. On failure the array is left intact.
+func (o *PackedArray) TrimToSizeE() (*PackedArray, error) {
+	words, err := o.words.TrimToSizeE()
+	if err != nil {
+		return nil, err
+	}
+	return o.moveTo(words), nil
+}
+
+func (o *PackedArray) moveTo(words *Array[uint64]) *PackedArray {
+	target := &PackedArray{
+		words: words,
+		width: o.width,
+		len:   o.len,
+	}
+
+	o.len = 0
+	return target
+}
+
+// Values calls callback once for each distinct value, in order of first occurrence.
+func (o *PackedArray) Values(callback func(uint64)) {
+	uniq := make(map[uint64]struct{})
+	for i := 0; i < o.Len(); i++ {
+		v := o.get(i)
+		if _, seen := uniq[v]; !seen {
+			uniq[v] = struct{}{}
+			callback(v)
+		}
+	}
+}
+
+// OnesCount returns the number of set bits in elements [from, to). For width 1, it is the number of elements set to 1.
+func (o *PackedArray) OnesCount(from, to int) int {
+	o.checkRange(from, to, o.Len())
+
+	lo, hi := uint64(from)*uint64(o.width), uint64(to)*uint64(o.width)
+	if lo == hi {
+		return 0
+	}
+
+	words := o.words.slice
+	first, last := lo/wordBits, (hi-1)/wordBits
+	headMask := ^uint64(0) << (lo % wordBits)
+	tailMask := ^uint64(0) >> (wordBits - 1 - (hi-1)%wordBits)
+
+	if first == last {
+		return bits.OnesCount64(words[first] & headMask & tailMask)
+	}
+
+	n := bits.OnesCount64(words[first]&headMask) + bits.OnesCount64(words[last]&tailMask)
+	for _, w := range words[first+1 : last] {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// extend adds n elements to the end, appending backing words as needed
+func (o *PackedArray) extend(n int) {
+	for need := wordsFor(o.width, o.len+n); o.words.Len() < need; {
+		o.words.Append(0)
+	}
+	o.len += n
+}
+
+// truncate drops elements past n along with backing words no longer needed
+func (o *PackedArray) truncate(n int) {
+	o.len = n
+	o.words.RemoveRange(wordsFor(o.width, n), o.words.Len())
+}
+
+func (o *PackedArray) get(i int) uint64 {
+	bit := uint64(i) * uint64(o.width)
+	w, off := bit/wordBits, uint(bit%wordBits)
+
+	words := o.words.slice
+	v := words[w] >> off
+	if off+o.width > wordBits {
+		v |= words[w+1] << (wordBits - off)
+	}
+	return v & o.Max()
+}
+
+func (o *PackedArray) set(i int, v uint64) {
+	bit := uint64(i) * uint64(o.width)
+	w, off := bit/wordBits, uint(bit%wordBits)
+
+	words, mask := o.words.slice, o.Max()
+	words[w] = words[w]&^(mask<<off) | v<<off
+	if off+o.width > wordBits {
+		words[w+1] = words[w+1]&^(mask>>(wordBits-off)) | v>>(wordBits-off)
+	}
+}
+
+// moveBitsUp moves bits [from, to) of words up by shift bits. Words are written from the highest one,
+// so that source bits are read before they are overwritten.
+func moveBitsUp(words []uint64, from, to, shift uint64) {
+	if from == to || shift == 0 {
+		return
+	}
+
+	lo, hi := from+shift, to+shift
+	for d := (hi - 1) / wordBits; ; d-- {
+		start := d * wordBits
+
+		mask := ^uint64(0)
+		if start < lo {
+			mask &= ^uint64(0) << (lo - start)
+		}
+		if start+wordBits > hi {
+			mask &= ^uint64(0) >> (start + wordBits - hi)
+		}
+
+		// Bits below zero are only read for positions below lo, which are masked
+		var v uint64
+		if start >= shift {
+			p := start - shift
+			w, off := p/wordBits, p%wordBits
+			v = words[w] >> off
+			if off != 0 && w+1 < uint64(len(words)) {
+				v |= words[w+1] << (wordBits - off)
+			}
+		} else if shift-start < wordBits {
+			v = words[0] << (shift - start)
+		}
+
+		words[d] = words[d]&^mask | v&mask
+		if start <= lo {
+			return
+		}
+	}
+}
+
+func (o *PackedArray) checkIndex(i, len int) {
+	if i < 0 || i >= len {
+		panic(fmt.Sprintf("offheap: index %d out of range [0:%d]", i, len))
+	}
+}
+
+func (o *PackedArray) checkRange(from, to, len int) {
+	if from < 0 || from > to || to > len {
+		panic(fmt.Sprintf("offheap: slice bounds [%d:%d] out of range [0:%d]", from, to, len))
+	}
+}
+
+func (o *PackedArray) checkFits(v uint64) {
+	if v > o.Max() {
+		panic(fmt.Sprintf("offheap: %d does not fit in %d bits", v, o.width))
+	}
+}
@@ -0,0 +1,169 @@
+package offheap
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackedArray_Widths(t *testing.T) {
+	for _, width := range []uint{1, 7, 12, 20, 33, 63, 64} {
+		a := NewPackedArray(width, 100)
+
+		expected := make([]uint64, 1000)
+		for i := range expected {
+			expected[i] = rand.Uint64() & a.Max()
+		}
+
+		a = a.Grow(len(expected))
+		for _, v := range expected {
+			a.Append(v)
+		}
+		for i, v := range expected {
+			require.Equal(t, v, a.Get(i), "width %d, index %d", width, i)
+		}
+
+		a = a.TrimToSize()
+		assert.Equal(t, wordsFor(width, len(expected)), a.words.Cap())
+		for i, v := range expected {
+			require.Equal(t, v, a.Get(i), "width %d, index %d", width, i)
+		}
+
+		a.Dealloc()
+	}
+}
+
+func TestPackedArray_Ops(t *testing.T) {
+	a := NewPackedArray(12, 16)
+	defer func() { a.Dealloc() }()
+
+	assert.Equal(t, uint64(4095), a.Max())
+	assert.Equal(t, 16, a.Cap())
+
+	a.AppendSlice([]uint64{1, 2, 3, 4, 5})
+	a.Insert(0, 4095)
+	a.InsertSlice(2, []uint64{10, 11})
+	assert.Equal(t, []uint64{4095, 1, 10, 11, 2, 3, 4, 5}, packedValues(a))
+
+	a.Swap(0, 7)
+	a.Set(1, 100)
+	assert.Equal(t, []uint64{5, 100, 10, 11, 2, 3, 4, 4095}, packedValues(a))
+
+	a.CopyFrom(2, a, 0, 4)
+	assert.Equal(t, []uint64{5, 100, 5, 100, 10, 11, 4, 4095}, packedValues(a))
+
+	a.RemoveRange(1, 4)
+	a.Fill(3, 5, 7)
+	assert.Equal(t, []uint64{5, 10, 11, 7, 7}, packedValues(a))
+
+	a.Remove(0)
+	assert.Equal(t, []uint64{10, 11, 7, 7}, packedValues(a))
+
+	var distinct []uint64
+	a.Values(func(v uint64) { distinct = append(distinct, v) })
+	assert.Equal(t, []uint64{10, 11, 7}, distinct)
+
+	assert.Panics(t, func() { a.Append(4096) })
+	assert.Panics(t, func() { a.Get(4) })
+}
+
+func TestPackedArray_Growth(t *testing.T) {
+	a, err := NewPackedArrayWithOptions(20, 0, Options{Growth: DefaultGrowthPolicy})
+	require.NoError(t, err)
+	defer func() { a.Dealloc() }()
+
+	for i := 0; i < 10_000; i++ {
+		a.Append(uint64(i))
+	}
+	assert.Equal(t, 10_000, a.Len())
+	assert.Equal(t, uint64(9_999), a.Get(9_999))
+
+	require.NoError(t, a.Reserve(100_000))
+	assert.GreaterOrEqual(t, a.Cap(), 110_000)
+}
+
+func TestNewPackedArray_Width(t *testing.T) {
+	_, err := NewPackedArrayE(0, 10)
+	assert.Error(t, err)
+
+	_, err = NewPackedArrayE(65, 10)
+	assert.Error(t, err)
+}
+
+func packedValues(a *PackedArray) (vs []uint64) {
+	for i := 0; i < a.Len(); i++ {
+		vs = append(vs, a.Get(i))
+	}
+	return
+}
+
+func TestPackedArray_OnesCount(t *testing.T) {
+	for _, width := range []uint{1, 3, 64} {
+		a := NewPackedArray(width, 200)
+
+		values := make([]uint64, 200)
+		for i := range values {
+			values[i] = rand.Uint64() & a.Max()
+		}
+		a.AppendSlice(values)
+
+		for _, r := range [][2]int{{0, 0}, {0, 200}, {5, 6}, {3, 64}, {63, 129}, {100, 200}} {
+			expected := 0
+			for _, v := range values[r[0]:r[1]] {
+				for ; v != 0; v &= v - 1 {
+					expected++
+				}
+			}
+			assert.Equal(t, expected, a.OnesCount(r[0], r[1]), "width %d, range %v", width, r)
+		}
+
+		assert.Panics(t, func() { a.OnesCount(10, 201) })
+		a.Dealloc()
+	}
+}
+
+func TestPackedArray_InsertShiftsWords(t *testing.T) {
+	for _, width := range []uint{1, 3, 13, 64} {
+		a, err := NewPackedArrayWithOptions(width, 8, Options{Growth: DefaultGrowthPolicy})
+		require.NoError(t, err)
+
+		var want []uint64
+		for i := 0; i < 500; i++ {
+			v := rand.Uint64() & a.Max()
+			at := rand.Intn(len(want) + 1)
+			if i%10 == 0 {
+				vs := []uint64{v, a.Max(), 0}
+				a.InsertSlice(at, vs)
+				want = append(want[:at], append(vs, want[at:]...)...)
+			} else {
+				a.Insert(at, v)
+				want = append(want[:at], append([]uint64{v}, want[at:]...)...)
+			}
+		}
+
+		got := make([]uint64, a.Len())
+		for i := range got {
+			got[i] = a.Get(i)
+		}
+		assert.Equal(t, want, got, "width %d", width)
+		a.Dealloc()
+	}
+}
+
+func TestPackedArray_Extend(t *testing.T) {
+	a := NewPackedArray(3, 100)
+	defer a.Dealloc()
+
+	a.AppendSlice([]uint64{7, 7, 7, 7})
+	a.RemoveRange(1, 4)
+	a.Extend(70)
+	assert.Equal(t, 71, a.Len())
+	assert.Equal(t, uint64(7), a.Get(0))
+	assert.Equal(t, 0, a.OnesCount(1, a.Len()))
+
+	a.Extend(0)
+	assert.Equal(t, 71, a.Len())
+	assert.Panics(t, func() { a.Extend(-1) })
+}
@@ -0,0 +1,192 @@
+package offheap
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// RecordArray is an off-heap array of fixed-size records, laid out back to back with a user-defined stride.
+// Records are accessed as byte slices, with typed values read and written by RecordField.
+type RecordArray struct {
+	data   *Array[byte]
+	stride int
+	swap   []byte // Scratch record for Swap
+}
+
+// NewRecordArray allocates an off-heap array with capacity for size records of stride bytes each.
+func NewRecordArray(stride, size int) *RecordArray {
+	return must(NewRecordArrayE(stride, size))
+}
+
+// NewRecordArrayE is like NewRecordArray, but returns an error instead of panicking.
+func NewRecordArrayE(stride, size int) (*RecordArray, error) {
+	return NewRecordArrayWithOptions(stride, size, Options{})
+}
+
+// NewRecordArrayWithOptions is like NewRecordArrayE, with the array mapped according to opts.
+// Options.Growth applies to the number of backing bytes.
+func NewRecordArrayWithOptions(stride, size int, opts Options) (*RecordArray, error) {
+	if stride < 1 {
+		return nil, fmt.Errorf("offheap: record stride %d is not positive", stride)
+	}
+	if size < 0 {
+		return nil, mapError("mmap", 0, syscall.EINVAL)
+	}
+
+	data, err := newArray[byte](recordType, stride*size, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordArray{data: data, stride: stride}, nil
+}
+
+// Stride returns the size of a record in bytes
+func (o *RecordArray) Stride() int {
+	return o.stride
+}
+
+func (o *RecordArray) Len() int {
+	return o.data.Len() / o.stride
+}
+
+func (o *RecordArray) Cap() int {
+	return o.data.Cap() / o.stride
+}
+
+func (o *RecordArray) Options() Options {
+	return o.data.Options()
+}
+
+func (o *RecordArray) Dealloc() {
+	o.data.Dealloc()
+}
+
+func (o *RecordArray) Close() error {
+	return o.data.Close()
+}
+
+func (o *RecordArray) Freeze() error {
+	return o.data.Freeze()
+}
+
+func (o *RecordArray) Frozen() bool {
+	return o.data.Frozen()
+}
+
+// Get returns the record at index i. The returned slice points into the array, so changing it changes the record.
+// It is only valid until the array is grown, trimmed or released.
+func (o *RecordArray) Get(i int) []byte {
+	o.data.checkLive()
+
+	start := i * o.stride
+	return o.data.slice[start : start+o.stride : start+o.stride]
+}
+
+// Set overwrites the record at index i with rec, which must be exactly Stride bytes long
+func (o *RecordArray) Set(i int, rec []byte) {
+	o.data.checkMutable()
+	o.checkRecord(rec)
+
+	copy(o.Get(i), rec)
+}
+
+func (o *RecordArray) Swap(i, j int) {
+	o.data.checkMutable()
+
+	if o.swap == nil {
+		o.swap = make([]byte, o.stride)
+	}
+
+	ri, rj := o.Get(i), o.Get(j)
+	copy(o.swap, ri)
+	copy(ri, rj)
+	copy(rj, o.swap)
+}
+
+// Append adds a record to the end, see Array.Append. The record must be exactly Stride bytes long.
+func (o *RecordArray) Append(rec []byte) {
+	o.checkRecord(rec)
+
+	o.data.AppendSlice(rec)
+}
+
+// Remove removes a record at index, preserving the order of remaining ones, see Array.Remove
+func (o *RecordArray) Remove(i int) {
+	o.data.checkMutable()
+
+	o.data.RemoveRange(i*o.stride, (i+1)*o.stride)
+}
+
+// Reserve makes sure at least n more records can be added without exceeding capacity, see Array.Reserve
+func (o *RecordArray) Reserve(n int) error {
+	return o.data.Reserve(n * o.stride)
+}
+
+func (o *RecordArray) Grow(size int) *RecordArray {
+	return must(o.GrowE(size))
+}
+
+// GrowE is like Grow, but returns an error instead of panicking. On failure the array is left intact.
+func (o *RecordArray) GrowE(size int) (*RecordArray, error) {
+	data, err := o.data.GrowE(size * o.stride)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordArray{data: data, stride: o.stride}, nil
+}
+
+func (o *RecordArray) TrimToSize() *RecordArray {
+	return must(o.TrimToSizeE())
+}
+
+// TrimToSizeE is like TrimToSize, but returns an error instead of panicking. On failure the array is left intact.
+func (o *RecordArray) TrimToSizeE() (*RecordArray, error) {
+	data, err := o.data.TrimToSizeE()
+	if err != nil {
+		return nil, err
+	}
+	return &RecordArray{data: data, stride: o.stride}, nil
+}
+
+func (o *RecordArray) checkRecord(rec []byte) {
+	if len(rec) != o.stride {
+		panic(fmt.Sprintf("offheap: record of %d bytes does not match stride %d", len(rec), o.stride))
+	}
+}
+
+// RecordField reads and writes values of type T at a fixed offset within records.
+// Records are not aligned for T, so values are copied in and out rather than referenced.
+// The zero RecordField is empty and reads zero values.
+type RecordField[T any] struct {
+	offset, size int
+}
+
+// NewRecordField returns a field of type T at offset within records of stride bytes.
+// T must be a fixed-size type without pointers, see NewArray.
+func NewRecordField[T any](stride, offset int) (RecordField[T], error) {
+	if err := checkElem[T](); err != nil {
+		return RecordField[T]{}, err
+	}
+
+	var kEl T
+	size := int(unsafe.Sizeof(kEl))
+	if offset < 0 || offset+size > stride {
+		return RecordField[T]{}, fmt.Errorf("offheap: %s of %d bytes at offset %d does not fit in stride %d",
+			typeName[T](), size, offset, stride)
+	}
+
+	return RecordField[T]{offset: offset, size: size}, nil
+}
+
+// Get returns the value of the field in rec
+func (f RecordField[T]) Get(rec []byte) (v T) {
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&v)), f.size), rec[f.offset:f.offset+f.size])
+	return
+}
+
+// Put sets the field in rec to v
+func (f RecordField[T]) Put(rec []byte, v T) {
+	copy(rec[f.offset:f.offset+f.size], unsafe.Slice((*byte)(unsafe.Pointer(&v)), f.size))
+}
@@ -0,0 +1,61 @@
+package offheap
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordArray(t *testing.T) {
+	a, err := NewRecordArrayWithOptions(12, 2, Options{Growth: DefaultGrowthPolicy})
+	require.NoError(t, err)
+
+	key, err := NewRecordField[uint64](12, 0)
+	require.NoError(t, err)
+	code, err := NewRecordField[[2]uint16](12, 8)
+	require.NoError(t, err)
+
+	rec := make([]byte, 12)
+	for i := 0; i < 100; i++ {
+		key.Put(rec, uint64(i))
+		code.Put(rec, [2]uint16{uint16(i), 7})
+		a.Append(rec)
+	}
+	assert.Equal(t, 100, a.Len())
+	assert.Panics(t, func() { a.Append(rec[:8]) })
+
+	// Records are not aligned, so fields of odd records are read by copying
+	assert.Equal(t, uint64(41), key.Get(a.Get(41)))
+	assert.Equal(t, [2]uint16{41, 7}, code.Get(a.Get(41)))
+	code.Put(a.Get(41), [2]uint16{1, 2})
+	assert.Equal(t, [2]uint16{1, 2}, code.Get(a.Get(41)))
+
+	a.Swap(0, 42)
+	assert.Equal(t, uint64(42), key.Get(a.Get(0)))
+	assert.Equal(t, uint64(0), binary.LittleEndian.Uint64(a.Get(42)))
+
+	a.Remove(0)
+	assert.Equal(t, 99, a.Len())
+	assert.Equal(t, uint64(1), key.Get(a.Get(0)))
+	assert.Equal(t, uint64(0), key.Get(a.Get(41)))
+
+	a = a.TrimToSize()
+	assert.Equal(t, 99, a.Cap())
+	assert.Equal(t, uint64(2), key.Get(a.Get(1)))
+
+	a.Dealloc()
+}
+
+func TestNewRecordField_Errors(t *testing.T) {
+	_, err := NewRecordField[uint64](12, 8)
+	assert.Error(t, err)
+	_, err = NewRecordField[uint64](12, -1)
+	assert.Error(t, err)
+	_, err = NewRecordField[*int](12, 0)
+	assert.Error(t, err)
+
+	var empty RecordField[uint64]
+	assert.Equal(t, uint64(0), empty.Get(make([]byte, 8)))
+}
@@ -0,0 +1,40 @@
+package offheap
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const mremapMayMove = 0x1
+
+// remap resizes the mapping in place to hold size elements, letting the kernel move it if needed.
+// Pages are remapped rather than copied, so no extra memory is needed. On failure the mapping is left intact,
+// except for an error applying Options to the remapped memory, which is returned with the mapping resized.
+func (o *array) remap(size int, _ int) error {
+	if o.data == nil || size == 0 {
+		return o.remapCopy(size, 0)
+	}
+
+	newSize := o.sz * uintptr(size)
+
+	data, _, errno := syscall.Syscall6(
+		syscall.SYS_MREMAP,
+		uintptr(o.data),
+		o.size(),
+		newSize,
+		mremapMayMove,
+		0,
+		0,
+	)
+	if errno != 0 {
+		return mapError("mremap", newSize, errno)
+	}
+
+	oldSize := o.size()
+
+	o.data = *(*unsafe.Pointer)(unsafe.Pointer(&data))
+	o.cap = size
+	account(o.typ, 0, int64(newSize)-int64(oldSize))
+
+	return o.advise(oldSize)
+}
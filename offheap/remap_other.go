@@ -0,0 +1,9 @@
+//go:build !linux
+
+package offheap
+
+// remap resizes the mapping to hold size elements, preserving the first len ones.
+// On failure the mapping is left intact.
+func (o *array) remap(size int, len int) error {
+	return o.remapCopy(size, len)
+}
@@ -0,0 +1,518 @@
+package offheap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ErrSnapshot is wrapped by errors reading a malformed, corrupted or incompatible snapshot
+var ErrSnapshot = errors.New("offheap: invalid snapshot")
+
+// SnapshotVersion is the version of snapshot format written by SnapshotWriter
+const SnapshotVersion = 1
+
+const (
+	snapshotMagic = "OFHSNAP\x00"
+
+	// snapshotAlign aligns column data in a snapshot, so that columns can be mapped on any page size
+	snapshotAlign = 64 << 10
+)
+
+// Column layouts
+const (
+	layoutRaw    uint32 = iota // Elements of Width bits each, in native byte order
+	layoutPacked               // Elements of Width bits each, packed into 64-bit words, see PackedArray
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// snapshotHeader starts a snapshot, followed by a table of columns and a CRC-32C of both.
+// Header and table are little-endian, column data is in byte order of the writer.
+type snapshotHeader struct {
+	Magic     [8]byte
+	Version   uint16
+	BigEndian uint8
+	_         uint8
+	Columns   uint32
+	Kind      [32]byte // Kind of stored structure, checked by readers
+}
+
+type snapshotColumn struct {
+	Layout uint32
+	Width  uint32 // Bits per element
+	Len    uint64 // Number of elements
+	Offset uint64 // Offset of data from the start of snapshot, aligned to snapshotAlign
+	Size   uint64 // Size of data in bytes
+	CRC    uint32 // CRC-32C of data
+	_      uint32
+}
+
+// SnapshotWriter writes off-heap arrays as columns of a snapshot, which can be read back with SnapshotReader
+// without parsing. Arrays must not be modified until the snapshot is written.
+type SnapshotWriter struct {
+	kind    string
+	columns []snapshotColumn
+	data    [][]byte
+}
+
+// NewSnapshotWriter creates a writer of a snapshot of kind, identifying the stored structure to readers
+func NewSnapshotWriter(kind string) *SnapshotWriter {
+	return &SnapshotWriter{kind: kind}
+}
+
+// AddArrayColumn adds elements of a to the snapshot as a column
+func AddArrayColumn[T any](w *SnapshotWriter, a *Array[T]) {
+	a.checkLive()
+
+	w.add(layoutRaw, uint32(a.sz*8), len(a.slice), sliceBytes(a.slice))
+}
+
+// AddPackedColumn adds elements of a to the snapshot as a column
+func (w *SnapshotWriter) AddPackedColumn(a *PackedArray) {
+	w.add(layoutPacked, uint32(a.width), a.Len(), sliceBytes(a.words.slice))
+}
+
+// AddRecordColumn adds records of a to the snapshot as a column
+func (w *SnapshotWriter) AddRecordColumn(a *RecordArray) {
+	a.data.checkLive()
+
+	w.add(layoutRaw, uint32(a.stride*8), a.Len(), a.data.slice)
+}
+
+// AddArenaColumns adds values of a to the snapshot as two columns, see SnapshotReader.ReadArenaColumns
+func (w *SnapshotWriter) AddArenaColumns(a *BytesArena) {
+	AddArrayColumn(w, a.data)
+	AddArrayColumn(w, a.ends)
+}
+
+func (w *SnapshotWriter) add(layout, width uint32, n int, data []byte) {
+	w.columns = append(w.columns, snapshotColumn{
+		Layout: layout,
+		Width:  width,
+		Len:    uint64(n),
+		Size:   uint64(len(data)),
+		CRC:    crc32.Checksum(data, crcTable),
+	})
+	w.data = append(w.data, data)
+}
+
+// WriteTo writes the snapshot to out, returning the number of bytes written
+func (w *SnapshotWriter) WriteTo(out io.Writer) (int64, error) {
+	hdr := snapshotHeader{Version: SnapshotVersion, Columns: uint32(len(w.columns))}
+	copy(hdr.Magic[:], snapshotMagic)
+	if len(w.kind) > len(hdr.Kind) {
+		return 0, fmt.Errorf("offheap: snapshot kind %q is too long", w.kind)
+	}
+	copy(hdr.Kind[:], w.kind)
+	if bigEndian {
+		hdr.BigEndian = 1
+	}
+
+	offset := alignSnapshot(snapshotTableSize(len(w.columns)))
+	for i := range w.columns {
+		w.columns[i].Offset = uint64(offset)
+		offset = alignSnapshot(offset + int64(w.columns[i].Size))
+	}
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, &hdr)
+	_ = binary.Write(&buf, binary.LittleEndian, w.columns)
+	_ = binary.Write(&buf, binary.LittleEndian, crc32.Checksum(buf.Bytes(), crcTable))
+
+	cw := &countingWriter{w: out}
+	if _, err := cw.Write(buf.Bytes()); err != nil {
+		return cw.n, err
+	}
+
+	for i, col := range w.columns {
+		if err := cw.pad(int64(col.Offset)); err != nil {
+			return cw.n, err
+		}
+		if _, err := cw.Write(w.data[i]); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// SnapshotReader reads columns of a snapshot written by SnapshotWriter, in order they were added
+type SnapshotReader struct {
+	r       *countingReader
+	path    string // Snapshot file, if columns are mapped rather than read, see OpenSnapshot
+	columns []snapshotColumn
+	next    int
+}
+
+// NewSnapshotReader reads a header of a snapshot from r, checking it holds a structure of kind
+func NewSnapshotReader(r io.Reader, kind string) (*SnapshotReader, error) {
+	cr := &countingReader{r: r}
+	columns, err := readSnapshotHeader(cr, kind)
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotReader{r: cr, columns: columns}, nil
+}
+
+// OpenSnapshot reads a header of a snapshot file, checking it holds a structure of kind.
+//
+// Columns are then mapped from the file read-only and shared instead of being read: arrays are frozen,
+// and their memory is backed by page cache, so it is shared by all processes opening the same file
+// and can be reclaimed by the kernel. Checksums are verified as columns are mapped.
+// The file must not be modified while mapped.
+func OpenSnapshot(path, kind string) (*SnapshotReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	columns, err := readSnapshotHeader(bufio.NewReader(f), kind)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &SnapshotReader{path: path, columns: columns}, nil
+}
+
+func readSnapshotHeader(r io.Reader, kind string) ([]snapshotColumn, error) {
+	var hdr snapshotHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, snapshotError("reading header: %v", err)
+	}
+
+	if string(hdr.Magic[:]) != snapshotMagic {
+		return nil, snapshotError("bad magic %q", hdr.Magic[:])
+	}
+	if hdr.Version != SnapshotVersion {
+		return nil, snapshotError("unsupported version %d", hdr.Version)
+	}
+	if (hdr.BigEndian == 1) != bigEndian {
+		return nil, snapshotError("written with a different byte order")
+	}
+	if got := string(bytes.TrimRight(hdr.Kind[:], "\x00")); got != kind {
+		return nil, snapshotError("holds %q, not %q", got, kind)
+	}
+	if hdr.Columns > 1<<10 {
+		return nil, snapshotError("too many columns: %d", hdr.Columns)
+	}
+
+	columns := make([]snapshotColumn, hdr.Columns)
+	if err := binary.Read(r, binary.LittleEndian, columns); err != nil {
+		return nil, snapshotError("reading columns: %v", err)
+	}
+
+	var sum uint32
+	if err := binary.Read(r, binary.LittleEndian, &sum); err != nil {
+		return nil, snapshotError("reading checksum: %v", err)
+	}
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, &hdr)
+	_ = binary.Write(&buf, binary.LittleEndian, columns)
+	if crc32.Checksum(buf.Bytes(), crcTable) != sum {
+		return nil, snapshotError("header checksum mismatch")
+	}
+
+	return columns, nil
+}
+
+// Columns returns the number of columns in the snapshot
+func (r *SnapshotReader) Columns() int {
+	return len(r.columns)
+}
+
+// Remaining returns the number of columns not read yet
+func (r *SnapshotReader) Remaining() int {
+	return len(r.columns) - r.next
+}
+
+// ReadArrayColumn reads the next column of the snapshot into a new array, mapped according to opts
+func ReadArrayColumn[T any](r *SnapshotReader, opts Options) (*Array[T], error) {
+	if err := checkElem[T](); err != nil {
+		return nil, err
+	}
+
+	return readArrayColumn[T](r, typeName[T](), opts)
+}
+
+// readArrayColumn is like ReadArrayColumn for arrays accounted in Stats under typ
+func readArrayColumn[T any](r *SnapshotReader, typ string, opts Options) (*Array[T], error) {
+	var kEl T
+	col, err := r.nextColumn(layoutRaw, uint32(unsafe.Sizeof(kEl)*8))
+	if err != nil {
+		return nil, err
+	}
+	if col.Size != col.Len*uint64(unsafe.Sizeof(kEl)) {
+		return nil, snapshotError("column of %d elements has %d bytes", col.Len, col.Size)
+	}
+
+	if r.path != "" {
+		return mapSnapshotColumn[T](r.path, col, typ, int(col.Len), opts)
+	}
+
+	a, err := newArray[T](typ, int(col.Len), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	a.slice = a.slice[:col.Len]
+	if err := r.readData(col, sliceBytes(a.slice)); err != nil {
+		a.Dealloc()
+		return nil, err
+	}
+	return a, nil
+}
+
+// ReadPackedColumn reads the next column of the snapshot into a new array, mapped according to opts
+func (r *SnapshotReader) ReadPackedColumn(opts Options) (*PackedArray, error) {
+	col, err := r.nextColumn(layoutPacked, 0)
+	if err != nil {
+		return nil, err
+	}
+	if col.Width < 1 || col.Width > wordBits {
+		return nil, snapshotError("column has %d-bit elements", col.Width)
+	}
+	if col.Size != uint64(wordsFor(uint(col.Width), int(col.Len)))*8 {
+		return nil, snapshotError("column of %d elements has %d bytes", col.Len, col.Size)
+	}
+
+	if r.path != "" {
+		words, err := mapSnapshotColumn[uint64](r.path, col, packedType, int(col.Size/8), opts)
+		if err != nil {
+			return nil, err
+		}
+		return &PackedArray{words: words, width: uint(col.Width), len: int(col.Len)}, nil
+	}
+
+	a, err := NewPackedArrayWithOptions(uint(col.Width), int(col.Len), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	a.words.slice = a.words.slice[:col.Size/8]
+	a.len = int(col.Len)
+	if err := r.readData(col, sliceBytes(a.words.slice)); err != nil {
+		a.Dealloc()
+		return nil, err
+	}
+	return a, nil
+}
+
+// ReadRecordColumn reads the next column of the snapshot into a new array of records of stride bytes,
+// mapped according to opts
+func (r *SnapshotReader) ReadRecordColumn(stride int, opts Options) (*RecordArray, error) {
+	if stride < 1 {
+		return nil, fmt.Errorf("offheap: record stride %d is not positive", stride)
+	}
+
+	col, err := r.nextColumn(layoutRaw, uint32(stride*8))
+	if err != nil {
+		return nil, err
+	}
+	if col.Size != col.Len*uint64(stride) {
+		return nil, snapshotError("column of %d records has %d bytes", col.Len, col.Size)
+	}
+
+	if r.path != "" {
+		data, err := mapSnapshotColumn[byte](r.path, col, recordType, int(col.Size), opts)
+		if err != nil {
+			return nil, err
+		}
+		return &RecordArray{data: data, stride: stride}, nil
+	}
+
+	data, err := newArray[byte](recordType, int(col.Size), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	data.slice = data.slice[:col.Size]
+	if err := r.readData(col, data.slice); err != nil {
+		data.Dealloc()
+		return nil, err
+	}
+	return &RecordArray{data: data, stride: stride}, nil
+}
+
+// ReadArenaColumns reads the next two columns of the snapshot into a new arena, mapped according to opts
+func (r *SnapshotReader) ReadArenaColumns(opts Options) (*BytesArena, error) {
+	if opts.Growth == (GrowthPolicy{}) {
+		opts.Growth = DefaultGrowthPolicy
+	}
+
+	data, err := readArrayColumn[byte](r, arenaType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ends, err := readArrayColumn[uint64](r, arenaType, opts)
+	if err != nil {
+		data.Dealloc()
+		return nil, err
+	}
+
+	a := &BytesArena{data: data, ends: ends}
+	if err := a.check(); err != nil {
+		a.Dealloc()
+		return nil, err
+	}
+	return a, nil
+}
+
+// nextColumn returns the next column, checking its layout and, unless zero, width
+func (r *SnapshotReader) nextColumn(layout, width uint32) (snapshotColumn, error) {
+	if r.next >= len(r.columns) {
+		return snapshotColumn{}, snapshotError("no more columns")
+	}
+
+	col := r.columns[r.next]
+	r.next++
+
+	if col.Layout != layout {
+		return col, snapshotError("column %d has layout %d, not %d", r.next-1, col.Layout, layout)
+	}
+	if width != 0 && col.Width != width {
+		return col, snapshotError("column %d has %d-bit elements, not %d-bit", r.next-1, col.Width, width)
+	}
+	return col, nil
+}
+
+func (r *SnapshotReader) readData(col snapshotColumn, data []byte) error {
+	if err := r.r.skip(int64(col.Offset)); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return snapshotError("reading column: %v", err)
+	}
+	if crc32.Checksum(data, crcTable) != col.CRC {
+		return snapshotError("column checksum mismatch")
+	}
+	return nil
+}
+
+// mapSnapshotColumn maps n elements of a column from a snapshot file into a frozen array accounted under typ
+func mapSnapshotColumn[T any](path string, col snapshotColumn, typ string, n int, opts Options) (*Array[T], error) {
+	if col.Offset%uint64(syscall.Getpagesize()) != 0 {
+		return nil, snapshotError("column at %d is not aligned to page size %d", col.Offset, syscall.Getpagesize())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := f.Stat(); err != nil || uint64(info.Size()) < col.Offset+col.Size {
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", path, snapshotError("truncated at %d bytes", info.Size()))
+	}
+
+	var kEl T
+
+	base := array{
+		sz:     unsafe.Sizeof(kEl),
+		file:   f,
+		offset: int64(col.Offset),
+		prot:   syscall.PROT_READ,
+		opts:   opts,
+		frozen: true,
+		typ:    typ,
+	}
+	if err := base.mapFile(n); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	a := track(&Array[T]{
+		array: base,
+		slice: unsafe.Slice((*T)(base.data), n),
+	})
+
+	if crc32.Checksum(sliceBytes(a.slice), crcTable) != col.CRC {
+		a.Dealloc()
+		return nil, fmt.Errorf("%s: %w", path, snapshotError("column checksum mismatch"))
+	}
+	return a, nil
+}
+
+func snapshotError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrSnapshot}, args...)...)
+}
+
+func snapshotTableSize(columns int) int64 {
+	return int64(binary.Size(snapshotHeader{})) + int64(columns*binary.Size(snapshotColumn{})) + 4
+}
+
+func alignSnapshot(offset int64) int64 {
+	return (offset + snapshotAlign - 1) &^ (snapshotAlign - 1)
+}
+
+// sliceBytes returns memory of s as bytes
+func sliceBytes[T any](s []T) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(s[0])))
+}
+
+var bigEndian = func() bool {
+	v := uint16(1)
+	return *(*byte)(unsafe.Pointer(&v)) == 0
+}()
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// pad writes zeroes up to offset
+func (w *countingWriter) pad(offset int64) error {
+	var zeroes [4096]byte
+	for w.n < offset {
+		n := offset - w.n
+		if n > int64(len(zeroes)) {
+			n = int64(len(zeroes))
+		}
+		if _, err := w.Write(zeroes[:n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// skip discards input up to offset
+func (r *countingReader) skip(offset int64) error {
+	if offset < r.n {
+		return snapshotError("column at %d overlaps preceding data", offset)
+	}
+	if _, err := io.CopyN(io.Discard, r, offset-r.n); err != nil {
+		return snapshotError("seeking column: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package offheap
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	type pair struct {
+		A uint32
+		B uint16
+	}
+
+	a := NewArray[pair](3)
+	a.Append(pair{1, 2})
+	a.Append(pair{3, 4})
+	defer a.Dealloc()
+
+	p := NewPackedArray(12, 100)
+	for i := 0; i < 100; i++ {
+		p.Append(uint64(i * 41))
+	}
+	defer p.Dealloc()
+
+	arena := NewBytesArena(2, 8)
+	arena.AppendString("one")
+	arena.AppendString("")
+	arena.AppendString("three")
+	defer arena.Dealloc()
+
+	empty := NewArray[uint64](0)
+	defer empty.Dealloc()
+
+	w := NewSnapshotWriter("test")
+	AddArrayColumn(w, a)
+	w.AddPackedColumn(p)
+	w.AddArenaColumns(arena)
+	AddArrayColumn(w, empty)
+
+	var buf bytes.Buffer
+	n, err := w.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	r, err := NewSnapshotReader(bytes.NewReader(buf.Bytes()), "test")
+	require.NoError(t, err)
+	assert.Equal(t, 5, r.Columns())
+
+	a2, err := ReadArrayColumn[pair](r, Options{})
+	require.NoError(t, err)
+	defer a2.Dealloc()
+	assert.Equal(t, 2, a2.Len())
+	assert.Equal(t, []pair{{1, 2}, {3, 4}}, []pair{a2.Get(0), a2.Get(1)})
+
+	p2, err := r.ReadPackedColumn(Options{})
+	require.NoError(t, err)
+	defer p2.Dealloc()
+	assert.Equal(t, uint(12), p2.Width())
+	assert.Equal(t, packedValues(p), packedValues(p2))
+
+	arena2, err := r.ReadArenaColumns(Options{})
+	require.NoError(t, err)
+	defer arena2.Dealloc()
+	assert.Equal(t, 3, arena2.Len())
+	assert.Equal(t, "three", arena2.GetString(2))
+	assert.Equal(t, 3, arena2.AppendString("four"))
+	assert.Equal(t, "four", arena2.GetString(3))
+
+	empty2, err := ReadArrayColumn[uint64](r, Options{})
+	require.NoError(t, err)
+	defer empty2.Dealloc()
+	assert.Equal(t, 0, empty2.Len())
+	assert.Equal(t, 0, r.Remaining())
+
+	_, err = ReadArrayColumn[uint64](r, Options{})
+	assert.ErrorIs(t, err, ErrSnapshot)
+}
+
+func TestSnapshot_Errors(t *testing.T) {
+	a := NewArray[uint32](4)
+	a.Append(1)
+	a.Append(2)
+	defer a.Dealloc()
+
+	w := NewSnapshotWriter("test")
+	AddArrayColumn(w, a)
+
+	var buf bytes.Buffer
+	_, err := w.WriteTo(&buf)
+	require.NoError(t, err)
+	data := buf.Bytes()
+
+	read := func(data []byte, kind string) error {
+		r, err := NewSnapshotReader(bytes.NewReader(data), kind)
+		if err != nil {
+			return err
+		}
+		a, err := ReadArrayColumn[uint32](r, Options{})
+		if err == nil {
+			a.Dealloc()
+		}
+		return err
+	}
+
+	require.NoError(t, read(data, "test"))
+	assert.ErrorIs(t, read(data, "other"), ErrSnapshot)
+	assert.ErrorIs(t, read(data[:10], "test"), ErrSnapshot)
+	assert.ErrorIs(t, read(data[:len(data)-1], "test"), ErrSnapshot)
+
+	corrupt := func(i int) []byte {
+		c := append([]byte(nil), data...)
+		c[i] ^= 0xff
+		return c
+	}
+	assert.ErrorIs(t, read(corrupt(8), "test"), ErrSnapshot)           // Version
+	assert.ErrorIs(t, read(corrupt(60), "test"), ErrSnapshot)          // Column table
+	assert.ErrorIs(t, read(corrupt(len(data)-1), "test"), ErrSnapshot) // Column data
+
+	r, err := NewSnapshotReader(bytes.NewReader(data), "test")
+	require.NoError(t, err)
+	_, err = ReadArrayColumn[uint64](r, Options{})
+	assert.True(t, errors.Is(err, ErrSnapshot))
+
+	_, err = NewSnapshotWriter("a kind name which is far too long to fit").WriteTo(&buf)
+	assert.Error(t, err)
+}
+
+func TestOpenSnapshot(t *testing.T) {
+	a := NewArray[uint32](4)
+	a.AppendSlice([]uint32{1, 2, 3})
+	defer a.Dealloc()
+
+	p := NewPackedArray(3, 10)
+	p.AppendSlice([]uint64{7, 0, 5})
+	defer p.Dealloc()
+
+	w := NewSnapshotWriter("test")
+	AddArrayColumn(w, a)
+	w.AddPackedColumn(p)
+
+	path := filepath.Join(t.TempDir(), "snapshot")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = w.WriteTo(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	r, err := OpenSnapshot(path, "test")
+	require.NoError(t, err)
+
+	a2, err := ReadArrayColumn[uint32](r, Options{Populate: true})
+	require.NoError(t, err)
+	defer a2.Dealloc()
+	assert.True(t, a2.Frozen())
+	assert.Equal(t, 3, a2.Len())
+	assert.Equal(t, uint32(3), a2.Get(2))
+	assert.PanicsWithValue(t, ErrFrozen, func() { a2.Set(0, 0) })
+
+	p2, err := r.ReadPackedColumn(Options{})
+	require.NoError(t, err)
+	defer p2.Dealloc()
+	assert.True(t, p2.Frozen())
+	assert.Equal(t, []uint64{7, 0, 5}, packedValues(p2))
+
+	_, err = OpenSnapshot(path, "other")
+	assert.ErrorIs(t, err, ErrSnapshot)
+
+	// Corrupted data is detected when mapped
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[snapshotAlign] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	r, err = OpenSnapshot(path, "test")
+	require.NoError(t, err)
+	_, err = ReadArrayColumn[uint32](r, Options{})
+	assert.ErrorIs(t, err, ErrSnapshot)
+
+	require.NoError(t, os.Truncate(path, snapshotAlign+4))
+	r, err = OpenSnapshot(path, "test")
+	require.NoError(t, err)
+	_, err = ReadArrayColumn[uint32](r, Options{})
+	assert.ErrorIs(t, err, ErrSnapshot)
+}
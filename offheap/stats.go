@@ -0,0 +1,146 @@
+package offheap
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of off-heap memory currently mapped by this package
+type Stats struct {
+	Mappings int    // Number of live mappings
+	Bytes    uint64 // Total size of live mappings
+
+	ByType map[string]TypeStats // Breakdown by element type, or by kind of array for those backing other structures
+}
+
+// Accounting names of arrays backing other structures, whose element types tell nothing about their use
+const (
+	interfaceType = "interface{}"
+	packedType    = "packed"
+	recordType    = "record"
+	arenaType     = "arena"
+)
+
+// TypeStats is a part of Stats related to arrays of a single element type
+type TypeStats struct {
+	Mappings int
+	Bytes    uint64
+}
+
+// Leak describes an array which was garbage collected without being deallocated
+type Leak struct {
+	Type  string // Element type, see Stats.ByType
+	Bytes uint64 // Size of the leaked mapping
+	Stack string // Stack trace of the allocation
+}
+
+var registry = struct {
+	sync.Mutex
+	byType map[string]TypeStats
+}{
+	byType: map[string]TypeStats{},
+}
+
+var (
+	debug       int32
+	leakHandler atomic.Value
+)
+
+// ReadStats returns current off-heap memory usage
+func ReadStats() Stats {
+	registry.Lock()
+	defer registry.Unlock()
+
+	stats := Stats{ByType: make(map[string]TypeStats, len(registry.byType))}
+	for typ, s := range registry.byType {
+		stats.Mappings += s.Mappings
+		stats.Bytes += s.Bytes
+		stats.ByType[typ] = s
+	}
+	return stats
+}
+
+// SetDebug enables leak detection for arrays allocated afterwards.
+// Allocation stack is recorded for each array, and arrays collected by GC without Dealloc are reported
+// to the leak handler and deallocated. This adds some overhead to allocation and should be used for debugging only.
+func SetDebug(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&debug, 1)
+	} else {
+		atomic.StoreInt32(&debug, 0)
+	}
+}
+
+// SetLeakHandler installs a callback for leaks found in debug mode. By default, leaks are logged.
+func SetLeakHandler(handler func(Leak)) {
+	leakHandler.Store(handler)
+}
+
+func account(typ string, mappings int, bytes int64) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	s := registry.byType[typ]
+	s.Mappings += mappings
+	s.Bytes = uint64(int64(s.Bytes) + bytes)
+
+	if s.Mappings == 0 && s.Bytes == 0 {
+		delete(registry.byType, typ)
+	} else {
+		registry.byType[typ] = s
+	}
+}
+
+// track arranges leak detection for an array which took ownership of a mapping
+func track[T any](o *Array[T]) *Array[T] {
+	if atomic.LoadInt32(&debug) == 0 {
+		return o
+	}
+
+	if o.stack == nil {
+		o.stack = make([]uintptr, 32)
+		o.stack = o.stack[:runtime.Callers(3, o.stack)]
+	}
+
+	runtime.SetFinalizer(o, (*Array[T]).finalize)
+	return o
+}
+
+func (o *Array[T]) finalize() {
+	if o.data == nil {
+		return
+	}
+
+	leak := Leak{
+		Type:  o.typ,
+		Bytes: uint64(o.size()),
+		Stack: formatStack(o.stack),
+	}
+
+	_ = o.Close()
+
+	if handler, _ := leakHandler.Load().(func(Leak)); handler != nil {
+		handler(leak)
+	} else {
+		log.Printf("offheap: %d bytes of []%s leaked, allocated at:\n%s", leak.Bytes, leak.Type, leak.Stack)
+	}
+}
+
+func formatStack(pcs []uintptr) string {
+	var buf strings.Builder
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		_, _ = fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+
+		if !more {
+			break
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,97 @@
+package offheap
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statsProbe struct {
+	A, B uint64
+}
+
+type leakProbe struct {
+	A uint32
+}
+
+func TestReadStats(t *testing.T) {
+	typ := typeName[statsProbe]()
+	assert.Equal(t, TypeStats{}, ReadStats().ByType[typ])
+
+	before := ReadStats()
+
+	a := NewArray[statsProbe](10)
+	b := NewArray[statsProbe](20)
+
+	stats := ReadStats()
+	assert.Equal(t, TypeStats{Mappings: 2, Bytes: 30 * 16}, stats.ByType[typ])
+	assert.Equal(t, before.Mappings+2, stats.Mappings)
+	assert.Equal(t, before.Bytes+30*16, stats.Bytes)
+
+	a = a.Grow(40)
+	assert.Equal(t, TypeStats{Mappings: 2, Bytes: 60 * 16}, ReadStats().ByType[typ])
+
+	a.Dealloc()
+	b.Dealloc()
+	assert.Equal(t, TypeStats{}, ReadStats().ByType[typ])
+}
+
+func TestReadStats_Wrappers(t *testing.T) {
+	before := ReadStats()
+
+	i := NewArrayInterface(4)
+	p := NewPackedArray(3, 64)
+	r := NewRecordArray(12, 2)
+	a := NewBytesArena(2, 16)
+
+	stats := ReadStats()
+	assert.Equal(t, before.ByType[interfaceType].Bytes+16, stats.ByType[interfaceType].Bytes)
+	assert.Equal(t, before.ByType[packedType].Bytes+24, stats.ByType[packedType].Bytes)
+	assert.Equal(t, before.ByType[recordType].Bytes+24, stats.ByType[recordType].Bytes)
+	assert.Equal(t, before.ByType[arenaType].Mappings+2, stats.ByType[arenaType].Mappings)
+	assert.Equal(t, before.ByType["uint64"], stats.ByType["uint64"])
+	assert.Equal(t, before.ByType["uint8"], stats.ByType["uint8"])
+
+	i.Dealloc()
+	p.Dealloc()
+	r.Dealloc()
+	a.Dealloc()
+	assert.Equal(t, before.ByType[packedType], ReadStats().ByType[packedType])
+}
+
+func TestSetDebug(t *testing.T) {
+	leaks := make(chan Leak, 1)
+
+	SetDebug(true)
+	SetLeakHandler(func(leak Leak) { leaks <- leak })
+	defer func() {
+		SetDebug(false)
+		SetLeakHandler(nil)
+	}()
+
+	func() {
+		a := NewArray[leakProbe](100)
+		a.Append(leakProbe{1})
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+
+		select {
+		case leak := <-leaks:
+			assert.Equal(t, typeName[leakProbe](), leak.Type)
+			assert.Equal(t, uint64(400), leak.Bytes)
+			assert.Contains(t, leak.Stack, "TestSetDebug")
+			assert.Equal(t, TypeStats{}, ReadStats().ByType[leak.Type])
+			return
+
+		case <-deadline:
+			t.Fatal("leak not reported")
+
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
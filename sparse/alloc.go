@@ -0,0 +1,28 @@
+package sparse
+
+import "github.com/andy722/structures/offheap"
+
+// resizable is implemented by off-heap arrays, which are replaced by a copy when resized
+type resizable[A any] interface {
+	GrowE(size int) (A, error)
+	TrimToSizeE() (A, error)
+}
+
+// trimArray replaces *a with a copy without spare capacity, keeping *a intact on failure
+func trimArray[A resizable[A]](a *A) error {
+	trimmed, err := (*a).TrimToSizeE()
+	if err != nil {
+		return err
+	}
+
+	*a = trimmed
+	return nil
+}
+
+// growthOptions makes backing arrays grow automatically by grow factor, unless opts define a growth policy already
+func growthOptions(opts offheap.Options, grow float64) offheap.Options {
+	if opts.Growth == (offheap.GrowthPolicy{}) {
+		opts.Growth = offheap.GrowthPolicy{Factor: grow, MinStep: offheap.DefaultGrowthPolicy.MinStep}
+	}
+	return opts
+}
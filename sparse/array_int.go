@@ -1,162 +1,73 @@
 package sparse
 
-import (
-	"github.com/andy722/structures/offheap"
-	"sort"
-)
+import "github.com/andy722/structures/offheap"
 
+// NoValue is returned by ArrayInt.Get for missing keys. It can be stored as well, see Map.Lookup.
 const NoValue int = -1
 
-// ArrayInt provides an off-heap map with numeric keys, internally represented as sparse array
+// ArrayInt provides an off-heap map with numeric keys, internally represented as sparse array, see Map
 type ArrayInt struct {
-	arrayUint64
-
-	values *offheap.ArrayInt
+	*Map[ArrayUint64Key, offheap.ArrayIntValue]
 }
 
 func NewSparseArrayInt(preallocate int, grow float64) *ArrayInt {
-	return &ArrayInt{
-		arrayUint64{
-			preallocate,
-			grow,
-			offheap.NewArrayUint64(preallocate),
-		},
-		offheap.NewArrayInt(preallocate),
-	}
-}
-
-func (s *ArrayInt) Close() {
-	s.arrayUint64.Close()
-	s.values.Dealloc()
-}
-
-func (s *ArrayInt) Add(key ArrayUint64Key, val int) {
-	i := s.idx(key)
-	if i < s.keys.Len() && s.keys.Get(i) == key {
-		s.values.Set(i, val)
-		return
+	s, err := NewSparseArrayIntE(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
-
-	s.growBackingArraysIfNeeded()
-
-	s.keys.Insert(i, key)
-	s.values.Insert(i, val)
-}
-
-func (s *ArrayInt) Get(key ArrayUint64Key) int {
-	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key {
-		return s.values.Get(i)
-	}
-	return NoValue
-}
-
-func (s *ArrayInt) Delete(key ArrayUint64Key) (prev int) {
-	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key {
-		prev = s.values.Get(i)
-		s.values.Set(i, NoValue)
-	}
-	return
-}
-
-func (s *ArrayInt) growBackingArraysIfNeeded() {
-	size := s.Size()
-	if size < s.cap() {
-		return
-	}
-
-	newSize := int(s.grow * float64(size))
-
-	s.keys = s.keys.Grow(newSize)
-	s.values = s.values.Grow(newSize)
+	return s
 }
 
-func (s *ArrayInt) cleanup() {
-	for i := s.Size() - 1; i >= 0; i-- {
-		if s.values.Get(i) == NoValue {
-			s.keys.Remove(i)
-			s.values.Remove(i)
-		}
-	}
+// NewSparseArrayIntE is like NewSparseArrayInt, but returns an error if off-heap memory cannot be allocated
+func NewSparseArrayIntE(preallocate int, grow float64) (*ArrayInt, error) {
+	return NewSparseArrayIntWithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (s *ArrayInt) shrink() {
-	if size := s.Size(); size < s.cap() {
-		s.keys = s.keys.TrimToSize()
-		s.values = s.values.TrimToSize()
+// NewSparseArrayIntWithOptions is like NewSparseArrayIntE, with backing arrays mapped according to opts
+func NewSparseArrayIntWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayInt, error) {
+	m, err := newMap[ArrayUint64Key](preallocate, grow, opts, newArrayColumn[offheap.ArrayIntValue], NoValue)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayInt{m}, nil
 }
 
 type ArrayIntBuilder struct {
-	s             *ArrayInt
-	shouldSort    bool // Marks as containing non-sorted data, need to sort prior to lookups
-	shouldCleanup bool // Marks as containing gaps, i.e. deleted entries
+	*Builder[ArrayUint64Key, offheap.ArrayIntValue]
 }
 
 //goland:noinspection GoUnusedExportedFunction
 func NewArrayIntBuilder(preallocate int, grow float64) *ArrayIntBuilder {
-	return &ArrayIntBuilder{
-		s: NewSparseArrayInt(preallocate, grow),
+	b, err := NewArrayIntBuilderE(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
+	return b
 }
 
-func (b *ArrayIntBuilder) Add(key ArrayUint64Key, value int) {
-	b.shouldSort = true
-
-	b.s.growBackingArraysIfNeeded()
-
-	b.s.keys.Append(key)
-	b.s.values.Append(value)
+func NewArrayIntBuilderE(preallocate int, grow float64) (*ArrayIntBuilder, error) {
+	return NewArrayIntBuilderWithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (b *ArrayIntBuilder) Delete(key ArrayUint64Key) {
-	if b.shouldSort {
-		b.sort()
-	}
-
-	if b.s.Delete(key) != NoValue {
-		b.shouldCleanup = true
+// NewArrayIntBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewArrayIntBuilderWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayIntBuilder, error) {
+	s, err := NewSparseArrayIntWithOptions(preallocate, grow, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayIntBuilder{&Builder[ArrayUint64Key, offheap.ArrayIntValue]{s: s.Map}}, nil
 }
 
 func (b *ArrayIntBuilder) Build() *ArrayInt {
-	if b.shouldCleanup {
-		b.s.cleanup()
-		b.shouldCleanup = false
-	}
-
-	if b.shouldSort {
-		b.sort()
-	}
-
-	b.s.shrink()
-
-	return b.s
+	return &ArrayInt{b.Builder.Build()}
 }
 
-func (b *ArrayIntBuilder) sort() {
-	sort.Sort(sparseArrayIntSorter(func() *ArrayInt { return b.s }))
-	b.shouldSort = false
-}
-
-type sparseArrayIntSorter func() *ArrayInt
-
-func (s sparseArrayIntSorter) Len() int {
-	return s().Size()
-}
-
-func (s sparseArrayIntSorter) Less(i, j int) bool {
-	keys := s().keys
-	return keys.Get(i) < keys.Get(j)
-}
-
-func (s sparseArrayIntSorter) Swap(i, j int) {
-	keys := s().keys
-	tmp := keys.Get(i)
-	keys.Set(i, keys.Get(j))
-	keys.Set(j, tmp)
-
-	values := s().values
-	tmp1 := values.Get(i)
-	values.Set(i, values.Get(j))
-	values.Set(j, tmp1)
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *ArrayIntBuilder) BuildE() (*ArrayInt, error) {
+	m, err := b.Builder.BuildE()
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayInt{m}, nil
 }
@@ -0,0 +1,160 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/range"
+	"sort"
+)
+
+// Ascend calls fn for each entry in ascending order of keys, until fn returns false
+func (s *Map[K, V]) Ascend(fn func(key K, val V) bool) {
+	s.ascend(0, s.Size(), fn)
+}
+
+// AscendRange calls fn for each entry with key in inclusive range [from, to] in ascending order,
+// until fn returns false
+func (s *Map[K, V]) AscendRange(from, to K, fn func(key K, val V) bool) {
+	lo, hi := s.span(from, to)
+	s.ascend(lo, hi, fn)
+}
+
+// Descend calls fn for each entry in descending order of keys, until fn returns false
+func (s *Map[K, V]) Descend(fn func(key K, val V) bool) {
+	for i := s.Size() - 1; i >= 0; i-- {
+		if !s.isDeleted(i) && !fn(s.keys.Get(i), s.values.Get(i)) {
+			return
+		}
+	}
+}
+
+// span returns indices [lo, hi) of keys in inclusive range [from, to]
+func (s *Map[K, V]) span(from, to K) (lo, hi int) {
+	if from > to {
+		return 0, 0
+	}
+
+	lo = s.idx(from)
+	hi = lo + sort.Search(s.Size()-lo, func(i int) bool { return s.keys.Get(lo+i) > to })
+	return lo, hi
+}
+
+func (s *Map[K, V]) ascend(lo, hi int, fn func(key K, val V) bool) {
+	for i := lo; i < hi; i++ {
+		if !s.isDeleted(i) && !fn(s.keys.Get(i), s.values.Get(i)) {
+			return
+		}
+	}
+}
+
+// Ascend calls fn for each entry in ascending order of keys, until fn returns false.
+// Values point into off-heap memory, see Get.
+func (s *ArrayBytes) Ascend(fn func(key ArrayUint64Key, val []byte) bool) {
+	s.refs.Ascend(s.deref(fn))
+}
+
+// AscendRange calls fn for each entry with key in inclusive range [from, to] in ascending order,
+// until fn returns false
+func (s *ArrayBytes) AscendRange(from, to ArrayUint64Key, fn func(key ArrayUint64Key, val []byte) bool) {
+	s.refs.AscendRange(from, to, s.deref(fn))
+}
+
+// Descend calls fn for each entry in descending order of keys, until fn returns false
+func (s *ArrayBytes) Descend(fn func(key ArrayUint64Key, val []byte) bool) {
+	s.refs.Descend(s.deref(fn))
+}
+
+func (s *ArrayBytes) deref(fn func(key ArrayUint64Key, val []byte) bool) func(ArrayUint64Key, uint32) bool {
+	return func(key ArrayUint64Key, ref uint32) bool {
+		return fn(key, s.arena.Get(int(ref)))
+	}
+}
+
+// Ascend calls fn for each range in ascending order, until fn returns false
+func (s *RangeMap[P]) Ascend(fn func(fromIncl, toIncl _range.RangePoint, payload P) bool) {
+	s.ascend(0, s.Size(), fn)
+}
+
+// AscendRange calls fn for each range overlapping inclusive range [from, to] in ascending order,
+// until fn returns false
+func (s *RangeMap[P]) AscendRange(from, to _range.RangePoint, fn func(fromIncl, toIncl _range.RangePoint, payload P) bool) {
+	lo, hi := overlapping(s.Size(), s.from.Get, s.endAt, from, to)
+	s.ascend(lo, hi, fn)
+}
+
+// Descend calls fn for each range in descending order, until fn returns false
+func (s *RangeMap[P]) Descend(fn func(fromIncl, toIncl _range.RangePoint, payload P) bool) {
+	for i := s.Size() - 1; i >= 0; i-- {
+		if rec := s.records.Get(i); !fn(s.from.Get(i), s.end.Get(rec), s.payload.Get(rec)) {
+			return
+		}
+	}
+}
+
+func (s *RangeMap[P]) ascend(lo, hi int, fn func(fromIncl, toIncl _range.RangePoint, payload P) bool) {
+	for i := lo; i < hi; i++ {
+		if rec := s.records.Get(i); !fn(s.from.Get(i), s.end.Get(rec), s.payload.Get(rec)) {
+			return
+		}
+	}
+}
+
+// Ascend calls fn for each range in ascending order, until fn returns false
+func (s *RangeStore) Ascend(fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool) {
+	s.m.Ascend(unpackRangeStoreValue(fn))
+}
+
+// AscendRange calls fn for each range overlapping inclusive range [from, to] in ascending order,
+// until fn returns false
+func (s *RangeStore) AscendRange(from, to _range.RangePoint, fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool) {
+	s.m.AscendRange(from, to, unpackRangeStoreValue(fn))
+}
+
+// Descend calls fn for each range in descending order, until fn returns false
+func (s *RangeStore) Descend(fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool) {
+	s.m.Descend(unpackRangeStoreValue(fn))
+}
+
+func unpackRangeStoreValue(
+	fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool,
+) func(_range.RangePoint, _range.RangePoint, rangeStoreValue) bool {
+	return func(fromIncl, toIncl _range.RangePoint, v rangeStoreValue) bool {
+		return fn(fromIncl, toIncl, v.v1, v.v2)
+	}
+}
+
+// Ascend calls fn for each range in ascending order, until fn returns false
+func (s *PackedRangeStore) Ascend(fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool) {
+	s.m.Ascend(unpackPackedRangeValue(fn))
+}
+
+// AscendRange calls fn for each range overlapping inclusive range [from, to] in ascending order,
+// until fn returns false
+func (s *PackedRangeStore) AscendRange(from, to _range.RangePoint, fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool) {
+	s.m.AscendRange(from, to, unpackPackedRangeValue(fn))
+}
+
+// Descend calls fn for each range in descending order, until fn returns false
+func (s *PackedRangeStore) Descend(fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool) {
+	s.m.Descend(unpackPackedRangeValue(fn))
+}
+
+func unpackPackedRangeValue(
+	fn func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool,
+) func(_range.RangePoint, _range.RangePoint, [2]uint64) bool {
+	return func(fromIncl, toIncl _range.RangePoint, v [2]uint64) bool {
+		return fn(fromIncl, toIncl, v[0], v[1])
+	}
+}
+
+// overlapping returns indices [lo, hi) of sorted non-overlapping ranges which overlap inclusive range [from, to]
+func overlapping(size int, start, end func(int) _range.RangePoint, from, to _range.RangePoint) (lo, hi int) {
+	if from > to {
+		return 0, 0
+	}
+
+	lo = sort.Search(size, func(i int) bool { return start(i) >= from })
+	if lo > 0 && end(lo-1) >= from {
+		lo--
+	}
+	hi = sort.Search(size, func(i int) bool { return start(i) > to })
+	return lo, hi
+}
@@ -0,0 +1,128 @@
+//go:build go1.23
+
+package sparse
+
+import (
+	"github.com/andy722/structures/range"
+	"iter"
+)
+
+// Range is an inclusive range [FromIncl, ToIncl], yielded as a key by range store iterators
+type Range struct {
+	FromIncl, ToIncl _range.RangePoint
+}
+
+// All returns an iterator over entries in ascending order of keys, see Ascend
+func (s *Map[K, V]) All() iter.Seq2[K, V] {
+	return s.Ascend
+}
+
+// Between returns an iterator over entries with key in inclusive range [from, to] in ascending order, see AscendRange
+func (s *Map[K, V]) Between(from, to K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.AscendRange(from, to, yield)
+	}
+}
+
+// Backward returns an iterator over entries in descending order of keys, see Descend
+func (s *Map[K, V]) Backward() iter.Seq2[K, V] {
+	return s.Descend
+}
+
+// All returns an iterator over entries in ascending order of keys, see Ascend
+func (s *ArrayBytes) All() iter.Seq2[ArrayUint64Key, []byte] {
+	return s.Ascend
+}
+
+// Between returns an iterator over entries with key in inclusive range [from, to] in ascending order, see AscendRange
+func (s *ArrayBytes) Between(from, to ArrayUint64Key) iter.Seq2[ArrayUint64Key, []byte] {
+	return func(yield func(ArrayUint64Key, []byte) bool) {
+		s.AscendRange(from, to, yield)
+	}
+}
+
+// Backward returns an iterator over entries in descending order of keys, see Descend
+func (s *ArrayBytes) Backward() iter.Seq2[ArrayUint64Key, []byte] {
+	return s.Descend
+}
+
+// All returns an iterator over ranges in ascending order, see Ascend
+func (s *RangeMap[P]) All() iter.Seq2[Range, P] {
+	return func(yield func(Range, P) bool) {
+		s.Ascend(func(fromIncl, toIncl _range.RangePoint, payload P) bool {
+			return yield(Range{fromIncl, toIncl}, payload)
+		})
+	}
+}
+
+// Between returns an iterator over ranges overlapping inclusive range [from, to] in ascending order, see AscendRange
+func (s *RangeMap[P]) Between(from, to _range.RangePoint) iter.Seq2[Range, P] {
+	return func(yield func(Range, P) bool) {
+		s.AscendRange(from, to, func(fromIncl, toIncl _range.RangePoint, payload P) bool {
+			return yield(Range{fromIncl, toIncl}, payload)
+		})
+	}
+}
+
+// Backward returns an iterator over ranges in descending order, see Descend
+func (s *RangeMap[P]) Backward() iter.Seq2[Range, P] {
+	return func(yield func(Range, P) bool) {
+		s.Descend(func(fromIncl, toIncl _range.RangePoint, payload P) bool {
+			return yield(Range{fromIncl, toIncl}, payload)
+		})
+	}
+}
+
+// All returns an iterator over ranges in ascending order, yielding pairs of values, see Ascend
+func (s *RangeStore) All() iter.Seq2[Range, [2]uint16] {
+	return func(yield func(Range, [2]uint16) bool) {
+		s.Ascend(func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool {
+			return yield(Range{fromIncl, toIncl}, [2]uint16{v1, v2})
+		})
+	}
+}
+
+// Between returns an iterator over ranges overlapping inclusive range [from, to] in ascending order, see AscendRange
+func (s *RangeStore) Between(from, to _range.RangePoint) iter.Seq2[Range, [2]uint16] {
+	return func(yield func(Range, [2]uint16) bool) {
+		s.AscendRange(from, to, func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool {
+			return yield(Range{fromIncl, toIncl}, [2]uint16{v1, v2})
+		})
+	}
+}
+
+// Backward returns an iterator over ranges in descending order, see Descend
+func (s *RangeStore) Backward() iter.Seq2[Range, [2]uint16] {
+	return func(yield func(Range, [2]uint16) bool) {
+		s.Descend(func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool {
+			return yield(Range{fromIncl, toIncl}, [2]uint16{v1, v2})
+		})
+	}
+}
+
+// All returns an iterator over ranges in ascending order, yielding pairs of values, see Ascend
+func (s *PackedRangeStore) All() iter.Seq2[Range, [2]uint64] {
+	return func(yield func(Range, [2]uint64) bool) {
+		s.Ascend(func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool {
+			return yield(Range{fromIncl, toIncl}, [2]uint64{v1, v2})
+		})
+	}
+}
+
+// Between returns an iterator over ranges overlapping inclusive range [from, to] in ascending order, see AscendRange
+func (s *PackedRangeStore) Between(from, to _range.RangePoint) iter.Seq2[Range, [2]uint64] {
+	return func(yield func(Range, [2]uint64) bool) {
+		s.AscendRange(from, to, func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool {
+			return yield(Range{fromIncl, toIncl}, [2]uint64{v1, v2})
+		})
+	}
+}
+
+// Backward returns an iterator over ranges in descending order, see Descend
+func (s *PackedRangeStore) Backward() iter.Seq2[Range, [2]uint64] {
+	return func(yield func(Range, [2]uint64) bool) {
+		s.Descend(func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool {
+			return yield(Range{fromIncl, toIncl}, [2]uint64{v1, v2})
+		})
+	}
+}
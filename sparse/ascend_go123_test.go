@@ -0,0 +1,61 @@
+//go:build go1.23
+
+package sparse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap_All(t *testing.T) {
+	b := NewBuilder[uint32, int64](4, DefaultGrow)
+	b.AddSlice([]uint32{3, 1, 2}, []int64{30, 10, 20})
+	s := b.Build()
+	defer s.Close()
+
+	var keys []uint32
+	for k, v := range s.All() {
+		assert.Equal(t, int64(k)*10, v)
+		keys = append(keys, k)
+	}
+	assert.Equal(t, []uint32{1, 2, 3}, keys)
+
+	keys = nil
+	for k := range s.Backward() {
+		keys = append(keys, k)
+		if k == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []uint32{3, 2}, keys)
+
+	keys = nil
+	for k := range s.Between(2, 3) {
+		keys = append(keys, k)
+	}
+	assert.Equal(t, []uint32{2, 3}, keys)
+}
+
+func TestRangeStore_All(t *testing.T) {
+	b := NewRangeStoreBuilder(4)
+	b.Add(10, 19, 1, 2)
+	b.Add(0, 9, 3, 4)
+	s := b.Build()
+	defer s.Close()
+
+	var ranges []Range
+	var values [][2]uint16
+	for r, v := range s.All() {
+		ranges = append(ranges, r)
+		values = append(values, v)
+	}
+	assert.Equal(t, []Range{{0, 9}, {10, 19}}, ranges)
+	assert.Equal(t, [][2]uint16{{3, 4}, {1, 2}}, values)
+
+	ranges = nil
+	for r := range s.Between(5, 6) {
+		ranges = append(ranges, r)
+	}
+	assert.Equal(t, []Range{{0, 9}}, ranges)
+}
@@ -0,0 +1,143 @@
+package sparse
+
+import (
+	"errors"
+	"github.com/andy722/structures/offheap"
+)
+
+// column is an off-heap array holding values of a map
+type column[V any] interface {
+	Len() int
+	Get(i int) V
+	Set(i int, v V)
+	Insert(i int, v V)
+	Append(v V)
+	AppendSlice(vs []V)
+	Swap(i, j int)
+	RemoveRange(from, to int)
+	Reserve(n int) error
+	Values(callback func(V))
+	Freeze() error
+	Frozen() bool
+	Dealloc()
+
+	// move copies elements [from, to) to index i within the column
+	move(i, from, to int)
+	// trim releases spare capacity, keeping the column intact on failure
+	trim() error
+	// snapshot adds the column to a snapshot
+	snapshot(w *offheap.SnapshotWriter) error
+	// permute reorders the column so that element order[i] moves to index i
+	permute(order *offheap.Array[uint32])
+}
+
+// errInterfaceSnapshot is returned on attempt to snapshot values kept on Go heap
+var errInterfaceSnapshot = errors.New("sparse: interface values cannot be written to a snapshot")
+
+// arrayColumn holds fixed-size values in offheap.Array
+type arrayColumn[T any] struct {
+	*offheap.Array[T]
+}
+
+func newArrayColumn[T any](size int, opts offheap.Options) (column[T], error) {
+	a, err := offheap.NewArrayWithOptions[T](size, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &arrayColumn[T]{a}, nil
+}
+
+func (c *arrayColumn[T]) move(i, from, to int) {
+	c.CopyFrom(i, c.Array, from, to)
+}
+
+func (c *arrayColumn[T]) trim() error {
+	return trimArray(&c.Array)
+}
+
+func (c *arrayColumn[T]) snapshot(w *offheap.SnapshotWriter) error {
+	offheap.AddArrayColumn(w, c.Array)
+	return nil
+}
+
+func (c *arrayColumn[T]) permute(order *offheap.Array[uint32]) {
+	permuteArray(&c.Array, order)
+}
+
+func readArrayColumn[T any](r *offheap.SnapshotReader, opts offheap.Options) (column[T], error) {
+	a, err := offheap.ReadArrayColumn[T](r, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &arrayColumn[T]{a}, nil
+}
+
+// interfaceColumn holds arbitrary values in offheap.ArrayInterface
+type interfaceColumn struct {
+	*offheap.ArrayInterface
+}
+
+func newInterfaceColumn(size int, opts offheap.Options) (column[interface{}], error) {
+	a, err := offheap.NewArrayInterfaceWithOptions(size, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &interfaceColumn{a}, nil
+}
+
+func (c *interfaceColumn) move(i, from, to int) {
+	c.CopyFrom(i, c.ArrayInterface, from, to)
+}
+
+func (c *interfaceColumn) trim() error {
+	return trimArray(&c.ArrayInterface)
+}
+
+func (c *interfaceColumn) snapshot(*offheap.SnapshotWriter) error {
+	return errInterfaceSnapshot
+}
+
+// permute swaps values in place, as copying them would have to acquire a handle for each one
+func (c *interfaceColumn) permute(order *offheap.Array[uint32]) {
+	swapInPlace(order, c.Swap)
+}
+
+// packedColumn holds integers of a fixed bit width in offheap.PackedArray
+type packedColumn struct {
+	*offheap.PackedArray
+}
+
+func newPackedColumn(width uint) func(int, offheap.Options) (column[uint64], error) {
+	return func(size int, opts offheap.Options) (column[uint64], error) {
+		a, err := offheap.NewPackedArrayWithOptions(width, size, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &packedColumn{a}, nil
+	}
+}
+
+func (c *packedColumn) move(i, from, to int) {
+	c.CopyFrom(i, c.PackedArray, from, to)
+}
+
+func (c *packedColumn) trim() error {
+	return trimArray(&c.PackedArray)
+}
+
+func (c *packedColumn) snapshot(w *offheap.SnapshotWriter) error {
+	w.AddPackedColumn(c.PackedArray)
+	return nil
+}
+
+func (c *packedColumn) permute(order *offheap.Array[uint32]) {
+	permutePacked(&c.PackedArray, order)
+}
+
+func readPackedColumn(r *offheap.SnapshotReader, opts offheap.Options) (column[uint64], error) {
+	a, err := r.ReadPackedColumn(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &packedColumn{a}, nil
+}
@@ -0,0 +1,291 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/offheap"
+	"math/bits"
+	"sync"
+)
+
+// ConcurrentMap is a Map safe for concurrent use by many readers and writers.
+// Keys are spread over shards by hash, each guarded by its own sync.RWMutex, so that writers
+// growing one shard don't block readers of others. Ordered queries are not supported.
+type ConcurrentMap[K Key, V any] struct {
+	shards []concurrentShard[K, V]
+	shift  int // Shard of a key is the top bits of its hash
+}
+
+type concurrentShard[K Key, V any] struct {
+	sync.RWMutex
+	m *Map[K, V]
+	_ [32]byte // Keep locks of adjacent shards on different cache lines
+}
+
+// NewConcurrentMap creates a map split into at least shards shards, with capacity for preallocate entries
+// in total, grown by grow factor as needed. See NewMap for requirements on V.
+func NewConcurrentMap[K Key, V any](shards, preallocate int, grow float64) *ConcurrentMap[K, V] {
+	s, err := NewConcurrentMapE[K, V](shards, preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewConcurrentMapE is like NewConcurrentMap, but returns an error if off-heap memory cannot be allocated
+// or V cannot be stored off-heap
+func NewConcurrentMapE[K Key, V any](shards, preallocate int, grow float64) (*ConcurrentMap[K, V], error) {
+	return NewConcurrentMapWithOptions[K, V](shards, preallocate, grow, offheap.Options{})
+}
+
+// NewConcurrentMapWithOptions is like NewConcurrentMapE, with backing arrays mapped according to opts
+func NewConcurrentMapWithOptions[K Key, V any](
+	shards, preallocate int,
+	grow float64,
+	opts offheap.Options,
+) (*ConcurrentMap[K, V], error) {
+	var noValue V
+	return NewConcurrentMapWithNoValue[K](shards, preallocate, grow, opts, noValue)
+}
+
+// NewConcurrentMapWithNoValue is like NewConcurrentMapWithOptions, with noValue returned for missing keys
+// instead of the zero value of V
+func NewConcurrentMapWithNoValue[K Key, V any](
+	shards, preallocate int,
+	grow float64,
+	opts offheap.Options,
+	noValue V,
+) (*ConcurrentMap[K, V], error) {
+	return newConcurrentMap(shards, preallocate, func(preallocate int) (*Map[K, V], error) {
+		return newMap[K](preallocate, grow, opts, newArrayColumn[V], noValue)
+	})
+}
+
+// NewConcurrentSparseArrayInt creates a concurrent counterpart of ArrayInt, see NewConcurrentMap
+func NewConcurrentSparseArrayInt(
+	shards, preallocate int,
+	grow float64,
+) *ConcurrentMap[ArrayUint64Key, offheap.ArrayIntValue] {
+	s, err := NewConcurrentSparseArrayIntE(shards, preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewConcurrentSparseArrayIntE is like NewConcurrentSparseArrayInt, but returns an error if off-heap memory
+// cannot be allocated
+func NewConcurrentSparseArrayIntE(
+	shards, preallocate int,
+	grow float64,
+) (*ConcurrentMap[ArrayUint64Key, offheap.ArrayIntValue], error) {
+	return NewConcurrentSparseArrayIntWithOptions(shards, preallocate, grow, offheap.Options{})
+}
+
+// NewConcurrentSparseArrayIntWithOptions is like NewConcurrentSparseArrayIntE, with backing arrays mapped
+// according to opts
+func NewConcurrentSparseArrayIntWithOptions(
+	shards, preallocate int,
+	grow float64,
+	opts offheap.Options,
+) (*ConcurrentMap[ArrayUint64Key, offheap.ArrayIntValue], error) {
+	return newConcurrentMap(shards, preallocate, func(preallocate int) (*Map[ArrayUint64Key, offheap.ArrayIntValue], error) {
+		s, err := NewSparseArrayIntWithOptions(preallocate, grow, opts)
+		if err != nil {
+			return nil, err
+		}
+		return s.Map, nil
+	})
+}
+
+// NewConcurrentSparseArrayUint16 creates a concurrent counterpart of ArrayUint16, see NewConcurrentMap
+func NewConcurrentSparseArrayUint16(
+	shards, preallocate int,
+	grow float64,
+) *ConcurrentMap[ArrayUint64Key, offheap.ArrayUint16Value] {
+	s, err := NewConcurrentSparseArrayUint16E(shards, preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewConcurrentSparseArrayUint16E is like NewConcurrentSparseArrayUint16, but returns an error if off-heap memory
+// cannot be allocated
+func NewConcurrentSparseArrayUint16E(
+	shards, preallocate int,
+	grow float64,
+) (*ConcurrentMap[ArrayUint64Key, offheap.ArrayUint16Value], error) {
+	return NewConcurrentSparseArrayUint16WithOptions(shards, preallocate, grow, offheap.Options{})
+}
+
+// NewConcurrentSparseArrayUint16WithOptions is like NewConcurrentSparseArrayUint16E, with backing arrays mapped
+// according to opts
+func NewConcurrentSparseArrayUint16WithOptions(
+	shards, preallocate int,
+	grow float64,
+	opts offheap.Options,
+) (*ConcurrentMap[ArrayUint64Key, offheap.ArrayUint16Value], error) {
+	return NewConcurrentMapWithNoValue[ArrayUint64Key](shards, preallocate, grow, opts, ArrayUint16NoValue)
+}
+
+// NewConcurrentSparseArray creates a concurrent counterpart of ArrayInterface, see NewConcurrentMap
+func NewConcurrentSparseArray(shards, preallocate int, grow float64) *ConcurrentMap[ArrayUint64Key, interface{}] {
+	s, err := NewConcurrentSparseArrayE(shards, preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewConcurrentSparseArrayE is like NewConcurrentSparseArray, but returns an error if off-heap memory
+// cannot be allocated
+func NewConcurrentSparseArrayE(
+	shards, preallocate int,
+	grow float64,
+) (*ConcurrentMap[ArrayUint64Key, interface{}], error) {
+	return NewConcurrentSparseArrayWithOptions(shards, preallocate, grow, offheap.Options{})
+}
+
+// NewConcurrentSparseArrayWithOptions is like NewConcurrentSparseArrayE, with backing arrays mapped
+// according to opts
+func NewConcurrentSparseArrayWithOptions(
+	shards, preallocate int,
+	grow float64,
+	opts offheap.Options,
+) (*ConcurrentMap[ArrayUint64Key, interface{}], error) {
+	return newConcurrentMap(shards, preallocate, func(preallocate int) (*Map[ArrayUint64Key, interface{}], error) {
+		s, err := NewSparseArrayWithOptions(preallocate, grow, opts)
+		if err != nil {
+			return nil, err
+		}
+		return s.Map, nil
+	})
+}
+
+// newConcurrentMap creates a map with the number of shards rounded up to a power of 2,
+// each allocated by newShard with its part of preallocate
+func newConcurrentMap[K Key, V any](
+	shards, preallocate int,
+	newShard func(preallocate int) (*Map[K, V], error),
+) (*ConcurrentMap[K, V], error) {
+	if shards < 1 {
+		shards = 1
+	}
+	n := bits.Len(uint(shards - 1))
+
+	s := &ConcurrentMap[K, V]{
+		shards: make([]concurrentShard[K, V], 1<<n),
+		shift:  64 - n,
+	}
+
+	perShard := (preallocate + len(s.shards) - 1) / len(s.shards)
+	for i := range s.shards {
+		m, err := newShard(perShard)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.shards[i].m = m
+	}
+	return s, nil
+}
+
+func (s *ConcurrentMap[K, V]) shard(key K) *concurrentShard[K, V] {
+	// Fibonacci hashing spreads sequential keys evenly, shifting by 64 leaves a single shard
+	return &s.shards[(uint64(key)*0x9e3779b97f4a7c15)>>s.shift]
+}
+
+// Get returns a value for key, or NoValue if it is missing
+func (s *ConcurrentMap[K, V]) Get(key K) V {
+	val, _ := s.Lookup(key)
+	return val
+}
+
+// Lookup returns a value for key and whether it is present, see Map.Lookup
+func (s *ConcurrentMap[K, V]) Lookup(key K) (V, bool) {
+	sh := s.shard(key)
+	sh.RLock()
+	defer sh.RUnlock()
+
+	return sh.m.Lookup(key)
+}
+
+func (s *ConcurrentMap[K, V]) Add(key K, val V) {
+	if err := s.AddE(key, val); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (s *ConcurrentMap[K, V]) AddE(key K, val V) error {
+	sh := s.shard(key)
+	sh.Lock()
+	defer sh.Unlock()
+
+	return sh.m.AddE(key, val)
+}
+
+// Update atomically replaces a value for key with the result of fn, which is called with the current value
+// and whether it is present. Other operations on keys of the same shard block until fn returns.
+func (s *ConcurrentMap[K, V]) Update(key K, fn func(val V, exists bool) V) error {
+	sh := s.shard(key)
+	sh.Lock()
+	defer sh.Unlock()
+
+	return sh.m.AddE(key, fn(sh.m.Lookup(key)))
+}
+
+// Delete removes key, returning its previous value or NoValue if it was missing
+func (s *ConcurrentMap[K, V]) Delete(key K) V {
+	prev, err := s.DeleteE(key)
+	if err != nil {
+		panic(err)
+	}
+	return prev
+}
+
+// DeleteE is like Delete, but returns an error if the bitmap of deleted entries cannot be allocated
+func (s *ConcurrentMap[K, V]) DeleteE(key K) (V, error) {
+	sh := s.shard(key)
+	sh.Lock()
+	defer sh.Unlock()
+
+	return sh.m.DeleteE(key)
+}
+
+// Size returns the number of entries, excluding deleted ones. It is not atomic with respect to concurrent writers.
+func (s *ConcurrentMap[K, V]) Size() (size int) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.RLock()
+		size += sh.m.Size() - sh.m.tombstones
+		sh.RUnlock()
+	}
+	return
+}
+
+// Compact removes deleted entries, which otherwise keep taking space in their shards.
+// Shards are compacted one at a time, each blocking operations on its keys meanwhile.
+func (s *ConcurrentMap[K, V]) Compact() {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.Lock()
+		if sh.m.tombstones > 0 {
+			sh.m.cleanup()
+		}
+		sh.Unlock()
+	}
+}
+
+// NoValue is returned by Get for missing keys
+func (s *ConcurrentMap[K, V]) NoValue() V {
+	return s.shards[0].m.NoValue()
+}
+
+// Close releases off-heap memory. The map must not be used concurrently with or after Close.
+func (s *ConcurrentMap[K, V]) Close() {
+	for i := range s.shards {
+		if m := s.shards[i].m; m != nil {
+			m.Close()
+		}
+	}
+}
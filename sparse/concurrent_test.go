@@ -0,0 +1,150 @@
+package sparse
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/andy722/structures/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentMap(t *testing.T) {
+	s := NewConcurrentSparseArrayInt(3, 16, DefaultGrow)
+	defer s.Close()
+	assert.Len(t, s.shards, 4)
+
+	s.Add(1, 10)
+	s.Add(2, NoValue)
+	assert.Equal(t, 10, s.Get(1))
+	v, ok := s.Lookup(2)
+	assert.True(t, ok)
+	assert.Equal(t, NoValue, v)
+	assert.Equal(t, NoValue, s.Get(3))
+	assert.Equal(t, 2, s.Size())
+
+	assert.Equal(t, 10, s.Delete(1))
+	assert.Equal(t, NoValue, s.Delete(1))
+	assert.Equal(t, 1, s.Size())
+
+	single := NewConcurrentSparseArray(0, 0, DefaultGrow)
+	defer single.Close()
+	single.Add(5, "5")
+	assert.Equal(t, "5", single.Get(5))
+	assert.Nil(t, single.Get(6))
+}
+
+func TestConcurrentMap_NoValue(t *testing.T) {
+	s := NewConcurrentSparseArrayUint16(2, 16, DefaultGrow)
+	defer s.Close()
+
+	s.Add(1, 0)
+	assert.Equal(t, ArrayUint16NoValue, s.NoValue())
+	assert.Equal(t, ArrayUint16NoValue, s.Get(2))
+	assert.Equal(t, uint16(0), s.Get(1))
+	assert.Equal(t, uint16(0), s.Delete(1))
+	assert.Equal(t, ArrayUint16NoValue, s.Delete(1))
+
+	f, err := NewConcurrentMapWithNoValue[ArrayUint32Key](2, 16, DefaultGrow, offheap.Options{}, float32(-1))
+	assert.NoError(t, err)
+	defer f.Close()
+	f.Add(1, 0)
+	assert.Equal(t, float32(-1), f.Get(2))
+	assert.Equal(t, float32(0), f.Get(1))
+}
+
+func TestConcurrentMap_Compact(t *testing.T) {
+	s := NewConcurrentSparseArrayInt(4, 16, DefaultGrow)
+	defer s.Close()
+
+	for k := ArrayUint64Key(0); k < 100; k++ {
+		s.Add(k, int(k))
+	}
+	for k := ArrayUint64Key(0); k < 100; k += 2 {
+		s.Delete(k)
+	}
+
+	s.Compact()
+	assert.Equal(t, 50, s.Size())
+	for i := range s.shards {
+		assert.Zero(t, s.shards[i].m.tombstones)
+		assert.Nil(t, s.shards[i].m.deleted)
+	}
+
+	for k := ArrayUint64Key(0); k < 100; k++ {
+		v, ok := s.Lookup(k)
+		assert.Equal(t, k%2 == 1, ok, k)
+		if ok {
+			assert.Equal(t, int(k), v)
+		}
+	}
+
+	s.Add(0, 7)
+	assert.Equal(t, 7, s.Get(0))
+	s.Compact()
+	assert.Equal(t, 51, s.Size())
+}
+
+func TestConcurrentMap_Concurrent(t *testing.T) {
+	const writers, keys = 4, 2000
+
+	s := NewConcurrentMap[ArrayUint32Key, uint64](8, 16, DefaultGrow)
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Each writer owns keys equal to w modulo writers, and counts all keys in an overlapping range
+			for k := ArrayUint32Key(w); k < keys; k += writers {
+				s.Add(k, uint64(k)*2)
+				if k%3 == 0 {
+					s.Delete(k)
+				}
+				assert.NoError(t, s.Update(keys+k%10, func(val uint64, _ bool) uint64 { return val + 1 }))
+			}
+		}(w)
+	}
+
+	var readers sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				for k := ArrayUint32Key(0); k < keys; k += 7 {
+					if v, ok := s.Lookup(k); ok && v != uint64(k)*2 {
+						t.Errorf("key %d: %d", k, v)
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	for k := ArrayUint32Key(0); k < keys; k++ {
+		v, ok := s.Lookup(k)
+		assert.Equal(t, k%3 != 0, ok, k)
+		if ok {
+			assert.Equal(t, uint64(k)*2, v)
+		}
+	}
+
+	var counted uint64
+	for k := ArrayUint32Key(keys); k < keys+10; k++ {
+		counted += s.Get(k)
+	}
+	assert.Equal(t, uint64(keys), counted)
+	assert.Equal(t, keys-(keys+2)/3+10, s.Size())
+}
@@ -0,0 +1,115 @@
+package sparse
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrHolderClosed is the panic value of Holder methods called after Close
+var ErrHolderClosed = errors.New("sparse: holder is closed")
+
+// Closer is implemented by off-heap structures, e.g. *ArrayInt or *RangeStore
+type Closer interface {
+	Close()
+}
+
+// Holder publishes a structure to concurrent readers and atomically replaces it with a rebuilt one.
+// Readers Acquire a reference to the current structure and Release it when done; a replaced structure
+// is closed once its last reader releases it, so its memory is never unmapped under a reader.
+type Holder[T Closer] struct {
+	current atomic.Value // *generation[T], nil after Close
+}
+
+// generation is a published structure with the number of references to it.
+// Holder owns a reference until the structure is replaced.
+type generation[T Closer] struct {
+	refs int64 // Accessed atomically, so it goes first to be 64-bit aligned on 32-bit platforms
+	v    T
+}
+
+// Ref is a reference to a structure published by Holder, valid until Release
+type Ref[T Closer] struct {
+	g *generation[T]
+}
+
+// NewHolder creates a Holder publishing v
+func NewHolder[T Closer](v T) *Holder[T] {
+	h := &Holder[T]{}
+	h.current.Store(&generation[T]{v: v, refs: 1})
+	return h
+}
+
+// Acquire returns a reference to the current structure, which must be released after use.
+// It never blocks and panics with ErrHolderClosed if the holder is closed.
+func (h *Holder[T]) Acquire() Ref[T] {
+	for {
+		g := h.current.Load().(*generation[T])
+		if g == nil {
+			panic(ErrHolderClosed)
+		}
+
+		// A generation without references is already retired, and a newer one is published
+		for refs := atomic.LoadInt64(&g.refs); refs > 0; refs = atomic.LoadInt64(&g.refs) {
+			if atomic.CompareAndSwapInt64(&g.refs, refs, refs+1) {
+				return Ref[T]{g}
+			}
+		}
+	}
+}
+
+// Do calls f with the current structure, holding a reference to it while f runs
+func (h *Holder[T]) Do(f func(T)) {
+	ref := h.Acquire()
+	defer ref.Release()
+
+	f(ref.Value())
+}
+
+// Swap publishes v, so that subsequent calls to Acquire return it.
+// The replaced structure is closed once all its readers release it. Swap panics with ErrHolderClosed
+// if the holder is closed, leaving v to the caller.
+func (h *Holder[T]) Swap(v T) {
+	h.replace(&generation[T]{v: v, refs: 1})
+}
+
+// Close unpublishes the current structure, which is closed once all its readers release it.
+// Swap and Acquire must not be called after Close.
+func (h *Holder[T]) Close() {
+	h.replace(nil)
+}
+
+func (h *Holder[T]) replace(g *generation[T]) {
+	for {
+		old := h.current.Load().(*generation[T])
+		if old == nil {
+			if g != nil {
+				panic(ErrHolderClosed)
+			}
+			return
+		}
+
+		if h.current.CompareAndSwap(old, g) {
+			old.release()
+			return
+		}
+	}
+}
+
+// Value returns the referenced structure. It must not be used after Release.
+func (r Ref[T]) Value() T {
+	return r.g.v
+}
+
+// Release drops the reference, closing the structure if it was replaced and this was its last reader.
+func (r Ref[T]) Release() {
+	r.g.release()
+}
+
+func (g *generation[T]) release() {
+	refs := atomic.AddInt64(&g.refs, -1)
+	if refs == 0 {
+		g.v.Close()
+	} else if refs < 0 {
+		panic("sparse: reference released twice")
+	}
+}
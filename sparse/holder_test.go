@@ -0,0 +1,86 @@
+package sparse
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/andy722/structures/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingCloser struct {
+	closed int32
+}
+
+func (c *countingCloser) Close() {
+	atomic.AddInt32(&c.closed, 1)
+}
+
+func TestHolder(t *testing.T) {
+	first, second := &countingCloser{}, &countingCloser{}
+	h := NewHolder(first)
+
+	ref := h.Acquire()
+	assert.Same(t, first, ref.Value())
+
+	h.Swap(second)
+	assert.Same(t, second, h.Acquire().Value())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&first.closed), "closed while in use")
+
+	ref.Release()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&first.closed))
+
+	h.Close()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&second.closed), "closed while in use")
+	assert.PanicsWithValue(t, ErrHolderClosed, func() { h.Acquire() })
+	assert.PanicsWithValue(t, ErrHolderClosed, func() { h.Swap(&countingCloser{}) })
+	h.Close()
+}
+
+func TestHolder_Concurrent(t *testing.T) {
+	build := func(v offheap.ArrayIntValue) *ArrayInt {
+		s := NewSparseArrayInt(16, DefaultGrow)
+		for k := ArrayUint64Key(0); k < 16; k++ {
+			s.Add(k, v)
+		}
+		return s
+	}
+
+	h := NewHolder(build(0))
+	defer h.Close()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				h.Do(func(s *ArrayInt) {
+					// All entries of a published map are consistent and never unmapped under a reader
+					v := s.Get(0)
+					for k := ArrayUint64Key(1); k < 16; k++ {
+						if s.Get(k) != v {
+							t.Errorf("key %d: %d != %d", k, s.Get(k), v)
+						}
+					}
+				})
+			}
+		}()
+	}
+
+	for v := offheap.ArrayIntValue(1); v <= 100; v++ {
+		h.Swap(build(v))
+	}
+	close(done)
+	wg.Wait()
+
+	h.Do(func(s *ArrayInt) { assert.Equal(t, offheap.ArrayIntValue(100), s.Get(15)) })
+}
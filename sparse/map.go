@@ -0,0 +1,477 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/offheap"
+	"sort"
+)
+
+type ArrayUint64Key = uint64
+type ArrayUint32Key = uint32
+
+// Key constrains types of Map keys
+type Key interface {
+	~uint32 | ~uint64
+}
+
+// Map provides an off-heap map with numeric keys, internally represented as sparse array:
+// sorted keys, and values at matching positions.
+// Deleted entries are tracked in a bitmap until the map is rebuilt, so any value can be stored.
+// Get returns NoValue for missing keys, use Lookup to tell them from stored NoValue.
+type Map[K Key, V any] struct {
+	keys    *offheap.Array[K]
+	values  column[V]
+	noValue V
+
+	deleted    *packedColumn // Bitmap of deleted entries, allocated on first Delete
+	tombstones int           // Number of entries marked in deleted
+	ranks      rankIndex     // Index of deleted for Rank and Select, nil until built after changes to deleted
+}
+
+// NewMap creates a map with capacity for preallocate entries, grown by grow factor as needed.
+// V must be a fixed-size type without pointers, and its zero value is used as NoValue.
+func NewMap[K Key, V any](preallocate int, grow float64) *Map[K, V] {
+	s, err := NewMapE[K, V](preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewMapE is like NewMap, but returns an error if off-heap memory cannot be allocated or V cannot be stored off-heap
+func NewMapE[K Key, V any](preallocate int, grow float64) (*Map[K, V], error) {
+	return NewMapWithOptions[K, V](preallocate, grow, offheap.Options{})
+}
+
+// NewMapWithOptions is like NewMapE, with backing arrays mapped according to opts
+func NewMapWithOptions[K Key, V any](preallocate int, grow float64, opts offheap.Options) (*Map[K, V], error) {
+	var noValue V
+	return newMap[K](preallocate, grow, opts, newArrayColumn[V], noValue)
+}
+
+// newMap creates a map with values kept in a column allocated by newValues
+func newMap[K Key, V any](
+	preallocate int,
+	grow float64,
+	opts offheap.Options,
+	newValues func(int, offheap.Options) (column[V], error),
+	noValue V,
+) (*Map[K, V], error) {
+	opts = growthOptions(opts, grow)
+
+	keys, err := offheap.NewArrayWithOptions[K](preallocate, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := newValues(preallocate, opts)
+	if err != nil {
+		keys.Dealloc()
+		return nil, err
+	}
+
+	return &Map[K, V]{
+		keys:    keys,
+		values:  values,
+		noValue: noValue,
+	}, nil
+}
+
+func (s *Map[K, V]) Size() int {
+	return s.keys.Len()
+}
+
+// NoValue is returned by Get for missing keys
+func (s *Map[K, V]) NoValue() V {
+	return s.noValue
+}
+
+func (s *Map[K, V]) Close() {
+	s.keys.Dealloc()
+	s.values.Dealloc()
+	if s.deleted != nil {
+		s.deleted.Dealloc()
+	}
+}
+
+// Freeze makes the map read-only, so that Add and Delete panic with offheap.ErrFrozen, see offheap.Array.Freeze
+func (s *Map[K, V]) Freeze() error {
+	if err := s.values.Freeze(); err != nil {
+		return err
+	}
+	if s.deleted != nil {
+		if err := s.deleted.Freeze(); err != nil {
+			return err
+		}
+		s.ranks = newRankIndex(s.deleted.PackedArray) // Not built lazily by concurrent readers
+	}
+	return s.keys.Freeze()
+}
+
+// Frozen tells if the map was made read-only with Freeze
+func (s *Map[K, V]) Frozen() bool {
+	return s.keys.Frozen()
+}
+
+func (s *Map[K, V]) checkMutable() {
+	if s.Frozen() {
+		panic(offheap.ErrFrozen)
+	}
+}
+
+func (s *Map[K, V]) Add(key K, val V) {
+	if err := s.AddE(key, val); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (s *Map[K, V]) AddE(key K, val V) error {
+	if s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	i := s.idx(key)
+	if i < s.Size() && s.keys.Get(i) == key {
+		s.values.Set(i, val)
+		s.undelete(i)
+		return nil
+	}
+
+	if err := s.reserve(1); err != nil {
+		return err
+	}
+
+	s.keys.Insert(i, key)
+	s.values.Insert(i, val)
+	if s.deleted != nil {
+		s.deleted.Insert(i, 0)
+		s.ranks = nil
+	}
+	return nil
+}
+
+// Get returns a value stored for key, or NoValue if there is none
+func (s *Map[K, V]) Get(key K) V {
+	val, _ := s.Lookup(key)
+	return val
+}
+
+// Lookup returns a value stored for key, and whether it exists. Unlike Get, it tells missing keys
+// from ones mapped to NoValue.
+func (s *Map[K, V]) Lookup(key K) (val V, exists bool) {
+	if i, ok := s.find(key); ok {
+		return s.values.Get(i), true
+	}
+	return s.noValue, false
+}
+
+// Delete removes a value stored for key, returning it, or NoValue if there was none
+func (s *Map[K, V]) Delete(key K) V {
+	prev, err := s.DeleteE(key)
+	if err != nil {
+		panic(err)
+	}
+	return prev
+}
+
+// DeleteE is like Delete, but returns an error if the bitmap of deleted entries cannot be allocated
+func (s *Map[K, V]) DeleteE(key K) (V, error) {
+	prev, _, err := s.remove(key)
+	return prev, err
+}
+
+// remove marks an entry of key as deleted, returning its value and whether it existed
+func (s *Map[K, V]) remove(key K) (prev V, exists bool, err error) {
+	s.checkMutable()
+
+	i, ok := s.find(key)
+	if !ok {
+		return s.noValue, false, nil
+	}
+
+	if s.deleted == nil {
+		deleted, err := offheap.NewPackedArrayWithOptions(1, s.cap(), s.keys.Options())
+		if err != nil {
+			return s.noValue, false, err
+		}
+		deleted.Extend(s.Size())
+		s.deleted = &packedColumn{deleted}
+	}
+
+	prev = s.values.Get(i)
+	s.values.Set(i, s.noValue) // Releases references held by the value
+	s.deleted.Set(i, 1)
+	s.tombstones++
+	s.ranks = nil
+	return prev, true, nil
+}
+
+// Values calls callback once for each distinct value, in order of first occurrence
+func (s *Map[K, V]) Values(callback func(V)) {
+	s.values.Values(func(v V) {
+		if !s.isNoValue(v) || s.storesNoValue() {
+			callback(v)
+		}
+	})
+}
+
+// storesNoValue tells if NoValue is stored for a key, rather than only left in place of deleted entries
+func (s *Map[K, V]) storesNoValue() bool {
+	for i := 0; i < s.Size(); i++ {
+		if !s.isDeleted(i) && s.isNoValue(s.values.Get(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Map[K, V]) isNoValue(v V) bool {
+	return any(v) == any(s.noValue)
+}
+
+func (s *Map[K, V]) idx(key K) int {
+	return sort.Search(s.Size(), func(i int) bool { return s.keys.Get(i) >= key })
+}
+
+// find returns an index of a live entry of key
+func (s *Map[K, V]) find(key K) (int, bool) {
+	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key && !s.isDeleted(i) {
+		return i, true
+	}
+	return 0, false
+}
+
+func (s *Map[K, V]) isDeleted(i int) bool {
+	return s.tombstones > 0 && s.deleted.Get(i) == 1
+}
+
+// undelete clears a deletion mark of entry i, if any
+func (s *Map[K, V]) undelete(i int) {
+	if s.isDeleted(i) {
+		s.deleted.Set(i, 0)
+		s.tombstones--
+		s.ranks = nil
+	}
+}
+
+func (s *Map[K, V]) cap() int {
+	return s.keys.Cap()
+}
+
+// reserve makes room for n more entries in backing arrays
+func (s *Map[K, V]) reserve(n int) error {
+	if err := s.keys.Reserve(n); err != nil {
+		return err
+	}
+	if s.deleted != nil {
+		if err := s.deleted.Reserve(n); err != nil {
+			return err
+		}
+	}
+	return s.values.Reserve(n)
+}
+
+// cleanup removes deleted entries, preserving order of the remaining ones
+func (s *Map[K, V]) cleanup() {
+	size := s.Size()
+	n := compact(size, s.isDeleted, func(i, from, to int) {
+		s.keys.CopyFrom(i, s.keys, from, to)
+		s.values.move(i, from, to)
+	})
+
+	s.keys.RemoveRange(n, size)
+	s.values.RemoveRange(n, size)
+
+	if s.deleted != nil {
+		s.deleted.Dealloc()
+		s.deleted = nil
+		s.tombstones = 0
+		s.ranks = nil
+	}
+}
+
+func (s *Map[K, V]) shrink() error {
+	if size := s.Size(); size < s.cap() {
+		if err := trimArray(&s.keys); err != nil {
+			return err
+		}
+		if s.deleted != nil {
+			if err := s.deleted.trim(); err != nil {
+				return err
+			}
+		}
+		return s.values.trim()
+	}
+	return nil
+}
+
+// Builder accumulates entries of a Map in any order, sorting them once on Build
+type Builder[K Key, V any] struct {
+	s             *Map[K, V]
+	shouldSort    bool // Marks as containing non-sorted data, need to sort prior to lookups
+	shouldCleanup bool // Marks as containing gaps, i.e. deleted entries
+}
+
+// NewBuilder creates a builder of a map with capacity for preallocate entries, see NewMap
+func NewBuilder[K Key, V any](preallocate int, grow float64) *Builder[K, V] {
+	b, err := NewBuilderE[K, V](preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func NewBuilderE[K Key, V any](preallocate int, grow float64) (*Builder[K, V], error) {
+	return NewBuilderWithOptions[K, V](preallocate, grow, offheap.Options{})
+}
+
+// NewBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewBuilderWithOptions[K Key, V any](preallocate int, grow float64, opts offheap.Options) (*Builder[K, V], error) {
+	s, err := NewMapWithOptions[K, V](preallocate, grow, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder[K, V]{s: s}, nil
+}
+
+func (b *Builder[K, V]) Add(key K, value V) {
+	if err := b.AddE(key, value); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (b *Builder[K, V]) AddE(key K, value V) error {
+	if b.s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	if err := b.s.reserve(1); err != nil {
+		return err
+	}
+
+	b.shouldSort = b.shouldSort || breaksOrder(b.s.keys, key)
+
+	b.s.values.Append(value)
+	b.s.keys.Append(key)
+	if b.s.deleted != nil {
+		b.s.deleted.Append(0)
+		b.s.ranks = nil
+	}
+	return nil
+}
+
+// AddSlice adds keys with corresponding values in bulk. Keys and values must be of the same length.
+func (b *Builder[K, V]) AddSlice(keys []K, values []V) {
+	if err := b.AddSliceE(keys, values); err != nil {
+		panic(err)
+	}
+}
+
+// AddSliceE is like AddSlice, but returns an error if backing arrays cannot be grown
+func (b *Builder[K, V]) AddSliceE(keys []K, values []V) error {
+	if len(keys) != len(values) {
+		panic("sparse: keys and values differ in length")
+	}
+
+	if b.s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	if err := b.s.reserve(len(keys)); err != nil {
+		return err
+	}
+
+	b.shouldSort = b.shouldSort || breaksOrder(b.s.keys, keys...)
+
+	b.s.values.AppendSlice(values)
+	b.s.keys.AppendSlice(keys)
+	if b.s.deleted != nil {
+		b.s.deleted.Extend(len(keys))
+		b.s.ranks = nil
+	}
+	return nil
+}
+
+func (b *Builder[K, V]) Delete(key K) {
+	if err := b.DeleteE(key); err != nil {
+		panic(err)
+	}
+}
+
+// DeleteE is like Delete, but returns an error if the bitmap of deleted entries cannot be allocated
+func (b *Builder[K, V]) DeleteE(key K) error {
+	if b.shouldSort {
+		b.sort()
+	}
+
+	_, exists, err := b.s.remove(key)
+	if exists {
+		b.shouldCleanup = true
+	}
+	return err
+}
+
+func (b *Builder[K, V]) Build() *Map[K, V] {
+	s, err := b.BuildE()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *Builder[K, V]) BuildE() (*Map[K, V], error) {
+	if b.shouldCleanup {
+		b.s.cleanup()
+		b.shouldCleanup = false
+	}
+
+	if b.shouldSort {
+		b.sort()
+	}
+
+	if err := b.s.shrink(); err != nil {
+		return nil, err
+	}
+
+	return b.s, nil
+}
+
+func (b *Builder[K, V]) sort() {
+	b.s.sort()
+	b.shouldSort = false
+}
+
+// sort orders entries by keys, see sortByKeys
+func (s *Map[K, V]) sort() {
+	sortByKeys(&s.keys, mapSorter[K, V](func() *Map[K, V] { return s }), func(order *offheap.Array[uint32]) {
+		s.values.permute(order)
+		if s.deleted != nil {
+			s.deleted.permute(order)
+			s.ranks = nil
+		}
+	})
+}
+
+type mapSorter[K Key, V any] func() *Map[K, V]
+
+func (s mapSorter[K, V]) Len() int {
+	return s().Size()
+}
+
+func (s mapSorter[K, V]) Less(i, j int) bool {
+	keys := s().keys
+	return keys.Get(i) < keys.Get(j)
+}
+
+func (s mapSorter[K, V]) Swap(i, j int) {
+	m := s()
+	m.keys.Swap(i, j)
+	m.values.Swap(i, j)
+	if m.deleted != nil {
+		m.deleted.Swap(i, j)
+		m.ranks = nil
+	}
+}
@@ -0,0 +1,76 @@
+package sparse
+
+// Floor returns the greatest key less than or equal to key, and its value
+func (s *Map[K, V]) Floor(key K) (K, V, bool) {
+	i := s.idx(key)
+	if i < s.Size() && s.keys.Get(i) == key {
+		return s.before(i)
+	}
+	return s.before(i - 1)
+}
+
+// Ceiling returns the least key greater than or equal to key, and its value
+func (s *Map[K, V]) Ceiling(key K) (K, V, bool) {
+	return s.after(s.idx(key))
+}
+
+// Lower returns the greatest key strictly less than key, and its value
+func (s *Map[K, V]) Lower(key K) (K, V, bool) {
+	return s.before(s.idx(key) - 1)
+}
+
+// Higher returns the least key strictly greater than key, and its value
+func (s *Map[K, V]) Higher(key K) (K, V, bool) {
+	i := s.idx(key)
+	if i < s.Size() && s.keys.Get(i) == key {
+		i++
+	}
+	return s.after(i)
+}
+
+// before returns the last live entry at index i or below
+func (s *Map[K, V]) before(i int) (key K, val V, ok bool) {
+	for ; i >= 0; i-- {
+		if !s.isDeleted(i) {
+			return s.keys.Get(i), s.values.Get(i), true
+		}
+	}
+	return key, s.noValue, false
+}
+
+// after returns the first live entry at index i or above
+func (s *Map[K, V]) after(i int) (key K, val V, ok bool) {
+	for ; i < s.Size(); i++ {
+		if !s.isDeleted(i) {
+			return s.keys.Get(i), s.values.Get(i), true
+		}
+	}
+	return key, s.noValue, false
+}
+
+// Floor returns the greatest key less than or equal to key, and its value, see Get
+func (s *ArrayBytes) Floor(key ArrayUint64Key) (ArrayUint64Key, []byte, bool) {
+	return s.derefEntry(s.refs.Floor(key))
+}
+
+// Ceiling returns the least key greater than or equal to key, and its value, see Get
+func (s *ArrayBytes) Ceiling(key ArrayUint64Key) (ArrayUint64Key, []byte, bool) {
+	return s.derefEntry(s.refs.Ceiling(key))
+}
+
+// Lower returns the greatest key strictly less than key, and its value, see Get
+func (s *ArrayBytes) Lower(key ArrayUint64Key) (ArrayUint64Key, []byte, bool) {
+	return s.derefEntry(s.refs.Lower(key))
+}
+
+// Higher returns the least key strictly greater than key, and its value, see Get
+func (s *ArrayBytes) Higher(key ArrayUint64Key) (ArrayUint64Key, []byte, bool) {
+	return s.derefEntry(s.refs.Higher(key))
+}
+
+func (s *ArrayBytes) derefEntry(key ArrayUint64Key, ref uint32, ok bool) (ArrayUint64Key, []byte, bool) {
+	if !ok {
+		return key, nil, false
+	}
+	return key, s.arena.Get(int(ref)), true
+}
@@ -0,0 +1,37 @@
+package sparse
+
+import "github.com/andy722/structures/offheap"
+
+// compact moves runs of entries which are not deleted towards the start in a single pass, preserving their order,
+// and returns the number of entries kept. Each run [from, to) is passed to move along with its new index.
+func compact(size int, deleted func(i int) bool, move func(i, from, to int)) int {
+	n := 0
+	for i := 0; i < size; {
+		if deleted(i) {
+			i++
+			continue
+		}
+
+		from := i
+		for i < size && !deleted(i) {
+			i++
+		}
+		if from != n {
+			move(n, from, i)
+		}
+		n += i - from
+	}
+	return n
+}
+
+// breaksOrder tells if appending keys to column would break its ascending order
+func breaksOrder[K ~uint32 | ~uint64](column *offheap.Array[K], keys ...K) bool {
+	for i, key := range keys {
+		if i > 0 && key < keys[i-1] {
+			return true
+		}
+	}
+
+	n := column.Len()
+	return n > 0 && len(keys) > 0 && keys[0] < column.Get(n-1)
+}
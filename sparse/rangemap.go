@@ -0,0 +1,299 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/offheap"
+	"github.com/andy722/structures/range"
+	"sort"
+	"unsafe"
+)
+
+// RangeMap maps inclusive range [fromIncl, toIncl] to a payload of any fixed-size type without pointers.
+// Range ends are stored contiguously with payloads, so a lookup touches a single record after searching range starts.
+// Records are not padded, e.g. a range with a pair of uint16 payload takes 20 bytes.
+type RangeMap[P any] struct {
+	from    *offheap.ArrayUint64
+	records *offheap.RecordArray // Range end followed by payload
+	end     offheap.RecordField[_range.RangePoint]
+	payload rangePayload[P]
+}
+
+// rangeEndSize is the number of bytes taken by a range end at the start of each record
+const rangeEndSize = 8
+
+// rangePayload reads and writes payloads of records, stored after range ends
+type rangePayload[P any] interface {
+	Get(rec []byte) P
+	Put(rec []byte, v P)
+}
+
+func NewRangeMap[P any](initialSize int, grow float64) RangeMap[P] {
+	s, err := NewRangeMapE[P](initialSize, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewRangeMapE is like NewRangeMap, but returns an error if off-heap memory cannot be allocated
+// or P cannot be stored off-heap
+func NewRangeMapE[P any](initialSize int, grow float64) (RangeMap[P], error) {
+	return NewRangeMapWithOptions[P](initialSize, grow, offheap.Options{})
+}
+
+// NewRangeMapWithOptions is like NewRangeMapE, with backing arrays mapped according to opts
+func NewRangeMapWithOptions[P any](initialSize int, grow float64, opts offheap.Options) (RangeMap[P], error) {
+	size, payload, err := fixedPayload[P]()
+	if err != nil {
+		return RangeMap[P]{}, err
+	}
+	return newRangeMap(initialSize, growthOptions(opts, grow), size, payload)
+}
+
+// newRangeMap creates a map with records holding payloads of size bytes
+func newRangeMap[P any](initialSize int, opts offheap.Options, size int, payload rangePayload[P]) (s RangeMap[P], err error) {
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	stride, err := s.layout(size, payload)
+	if err != nil {
+		return
+	}
+
+	if s.from, err = offheap.NewArrayUint64WithOptions(initialSize, opts); err != nil {
+		return
+	}
+	s.records, err = offheap.NewRecordArrayWithOptions(stride, initialSize, opts)
+	return
+}
+
+// fixedPayload returns a payload of P stored as is, along with its size
+func fixedPayload[P any]() (int, rangePayload[P], error) {
+	var p P
+	size := int(unsafe.Sizeof(p))
+
+	f, err := offheap.NewRecordField[P](rangeEndSize+size, rangeEndSize)
+	return size, f, err
+}
+
+// layout sets up fields of records holding payloads of size bytes, returning their stride
+func (s *RangeMap[P]) layout(size int, payload rangePayload[P]) (stride int, err error) {
+	stride = rangeEndSize + size
+	s.end, err = offheap.NewRecordField[_range.RangePoint](stride, 0)
+	s.payload = payload
+	return
+}
+
+func (s *RangeMap[P]) Get(key ArrayUint64Key) (payload P, exists bool) {
+	idx := sort.Search(s.Size(), func(i int) bool { return s.from.Get(i) >= key })
+	if idx >= s.Size() {
+		// Check if the last element matches
+		return s.checkMatch(key, idx-1)
+	}
+
+	if payload, exists = s.checkMatch(key, idx); exists {
+		return
+	}
+
+	if idx > 0 {
+		return s.checkMatch(key, idx-1)
+	}
+
+	return
+}
+
+func (s *RangeMap[P]) checkMatch(key ArrayUint64Key, idx int) (payload P, exists bool) {
+	if idx < 0 {
+		return
+	}
+
+	if rangeStart := s.from.Get(idx); rangeStart > key {
+		return
+	}
+
+	if rec := s.records.Get(idx); s.end.Get(rec) >= key {
+		return s.payload.Get(rec), true
+	}
+
+	return
+}
+
+// Values calls callback once for each distinct payload, in order of first occurrence. Payloads must be comparable.
+func (s *RangeMap[P]) Values(callback func(P)) {
+	uniq := make(map[interface{}]struct{})
+	for i := 0; i < s.Size(); i++ {
+		v := s.payloadAt(i)
+		if _, seen := uniq[v]; !seen {
+			uniq[v] = struct{}{}
+			callback(v)
+		}
+	}
+}
+
+// rangeValues calls callback once for each distinct value of field of payloads, in order of first occurrence
+func rangeValues[P any, V comparable](s *RangeMap[P], callback func(V), field func(P) V) {
+	uniq := make(map[V]struct{})
+	for i := 0; i < s.Size(); i++ {
+		v := field(s.payloadAt(i))
+		if _, seen := uniq[v]; !seen {
+			uniq[v] = struct{}{}
+			callback(v)
+		}
+	}
+}
+
+// endAt returns the end of i-th range
+func (s *RangeMap[P]) endAt(i int) _range.RangePoint {
+	return s.end.Get(s.records.Get(i))
+}
+
+// payloadAt returns the payload of i-th range
+func (s *RangeMap[P]) payloadAt(i int) P {
+	return s.payload.Get(s.records.Get(i))
+}
+
+func (s *RangeMap[P]) Size() int {
+	return s.from.Len()
+}
+
+func (s *RangeMap[P]) cap() int {
+	return s.from.Cap()
+}
+
+func (s *RangeMap[P]) shrink() error {
+	if size := s.Size(); size < s.cap() {
+		if err := trimArray(&s.from); err != nil {
+			return err
+		}
+		return trimArray(&s.records)
+	}
+	return nil
+}
+
+// reserve makes room for n more ranges in backing arrays
+func (s *RangeMap[P]) reserve(n int) error {
+	if err := s.from.Reserve(n); err != nil {
+		return err
+	}
+	return s.records.Reserve(n)
+}
+
+// Freeze makes the map read-only, see offheap.Array.Freeze
+func (s *RangeMap[P]) Freeze() error {
+	if err := s.records.Freeze(); err != nil {
+		return err
+	}
+	return s.from.Freeze()
+}
+
+// Frozen tells if the map was made read-only with Freeze
+func (s *RangeMap[P]) Frozen() bool {
+	return s.from.Frozen()
+}
+
+func (s *RangeMap[P]) Close() {
+	if s.from != nil {
+		s.from.Dealloc()
+	}
+	if s.records != nil {
+		s.records.Dealloc()
+	}
+}
+
+type RangeMapBuilder[P any] struct {
+	s          RangeMap[P]
+	shouldSort bool
+	rec        []byte // Record being appended
+}
+
+func NewRangeMapBuilder[P any](initialSize int) RangeMapBuilder[P] {
+	return RangeMapBuilder[P]{
+		s: NewRangeMap[P](initialSize, DefaultGrow),
+	}
+}
+
+func NewRangeMapBuilderE[P any](initialSize int) (RangeMapBuilder[P], error) {
+	return NewRangeMapBuilderWithOptions[P](initialSize, offheap.Options{})
+}
+
+// NewRangeMapBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built map.
+func NewRangeMapBuilderWithOptions[P any](initialSize int, opts offheap.Options) (RangeMapBuilder[P], error) {
+	s, err := NewRangeMapWithOptions[P](initialSize, DefaultGrow, opts)
+	return RangeMapBuilder[P]{s: s}, err
+}
+
+func (b *RangeMapBuilder[P]) Add(fromIncl, toIncl _range.RangePoint, payload P) {
+	if err := b.AddE(fromIncl, toIncl, payload); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (b *RangeMapBuilder[P]) AddE(fromIncl, toIncl _range.RangePoint, payload P) error {
+	if b.s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	if err := b.s.reserve(1); err != nil {
+		return err
+	}
+
+	b.shouldSort = b.shouldSort || breaksOrder(b.s.from, fromIncl)
+
+	if b.rec == nil {
+		b.rec = make([]byte, b.s.records.Stride())
+	}
+	b.s.end.Put(b.rec, toIncl)
+	b.s.payload.Put(b.rec, payload)
+
+	b.s.from.Append(fromIncl)
+	b.s.records.Append(b.rec)
+	return nil
+}
+
+func (b *RangeMapBuilder[P]) Build() RangeMap[P] {
+	s, err := b.BuildE()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *RangeMapBuilder[P]) BuildE() (RangeMap[P], error) {
+	if b.shouldSort {
+		b.sort()
+	}
+
+	if err := b.s.shrink(); err != nil {
+		return RangeMap[P]{}, err
+	}
+
+	return b.s, nil
+}
+
+func (b *RangeMapBuilder[P]) sort() {
+	sortByKeys(&b.s.from, rangeMapSorter[P](func() *RangeMap[P] { return &b.s }), func(order *offheap.Array[uint32]) {
+		permuteRecords(&b.s.records, order)
+	})
+	b.shouldSort = false
+}
+
+type rangeMapSorter[P any] func() *RangeMap[P]
+
+func (s rangeMapSorter[P]) Len() int {
+	return s().Size()
+}
+
+func (s rangeMapSorter[P]) Less(i, j int) bool {
+	keys := s().from
+	return keys.Get(i) < keys.Get(j)
+}
+
+func (s rangeMapSorter[P]) Swap(i, j int) {
+	s().from.Swap(i, j)
+	s().records.Swap(i, j)
+}
@@ -0,0 +1,97 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/offheap"
+	"sort"
+)
+
+// rankBlock is the number of entries per block of the deletion bitmap counted in a rankIndex
+const rankBlock = 512
+
+// rankIndex holds the number of deleted entries before each block of rankBlock entries,
+// so that deleted entries are counted by scanning at most a single block of the bitmap
+type rankIndex []int
+
+func newRankIndex(deleted *offheap.PackedArray) rankIndex {
+	n := deleted.Len()
+	r := make(rankIndex, n/rankBlock+1)
+	for b := 1; b < len(r); b++ {
+		r[b] = r[b-1] + deleted.OnesCount((b-1)*rankBlock, b*rankBlock)
+	}
+	return r
+}
+
+// deletedBefore returns the number of entries in [0, i) marked in deleted
+func (r rankIndex) deletedBefore(deleted *offheap.PackedArray, i int) int {
+	b := i / rankBlock
+	return r[b] + deleted.OnesCount(b*rankBlock, i)
+}
+
+// Rank returns the number of keys strictly less than key.
+// Rank, Select and CountRange take logarithmic time, but the first of them called after Delete rebuilds
+// an index of deleted entries, so they are only safe for concurrent use on a frozen map.
+func (s *Map[K, V]) Rank(key K) int {
+	return s.live(0, s.idx(key))
+}
+
+// Select returns the k-th smallest key, counting from 0, and its value, see Rank
+func (s *Map[K, V]) Select(k int) (key K, val V, ok bool) {
+	if k < 0 || k >= s.Size()-s.tombstones {
+		return key, s.noValue, false
+	}
+
+	i := k
+	if s.tombstones > 0 {
+		// The last block starting with at most k live entries before it holds the k-th one
+		r := s.rankIndex()
+		b := sort.Search(len(r), func(b int) bool { return b*rankBlock-r[b] > k }) - 1
+
+		// The first index within the block with k+1 live entries at or below it
+		lo := b * rankBlock
+		hi := lo + rankBlock
+		if hi > s.Size() {
+			hi = s.Size()
+		}
+		i = lo + sort.Search(hi-lo, func(i int) bool { return s.live(0, lo+i+1) > k })
+	}
+	return s.keys.Get(i), s.values.Get(i), true
+}
+
+// CountRange returns the number of keys in inclusive range [from, to], see Rank
+func (s *Map[K, V]) CountRange(from, to K) int {
+	return s.live(s.span(from, to))
+}
+
+// live returns the number of entries in [from, to) not marked as deleted
+func (s *Map[K, V]) live(from, to int) int {
+	if s.tombstones == 0 {
+		return to - from
+	}
+
+	r := s.rankIndex()
+	deleted := s.deleted.PackedArray
+	return to - from - (r.deletedBefore(deleted, to) - r.deletedBefore(deleted, from))
+}
+
+// rankIndex returns an index of the deletion bitmap, rebuilding it if the bitmap was changed
+func (s *Map[K, V]) rankIndex() rankIndex {
+	if s.ranks == nil {
+		s.ranks = newRankIndex(s.deleted.PackedArray)
+	}
+	return s.ranks
+}
+
+// Rank returns the number of keys strictly less than key, see Map.Rank
+func (s *ArrayBytes) Rank(key ArrayUint64Key) int {
+	return s.refs.Rank(key)
+}
+
+// Select returns the k-th smallest key, counting from 0, and its value, see Get
+func (s *ArrayBytes) Select(k int) (ArrayUint64Key, []byte, bool) {
+	return s.derefEntry(s.refs.Select(k))
+}
+
+// CountRange returns the number of keys in inclusive range [from, to]
+func (s *ArrayBytes) CountRange(from, to ArrayUint64Key) int {
+	return s.refs.CountRange(from, to)
+}
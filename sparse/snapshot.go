@@ -0,0 +1,380 @@
+package sparse
+
+import (
+	"fmt"
+	"github.com/andy722/structures/offheap"
+	"io"
+)
+
+// Kinds of snapshots, see offheap.SnapshotWriter
+const (
+	mapKind              = "sparse.Map"
+	arrayBytesKind       = "sparse.ArrayBytes"
+	rangeMapKind         = "sparse.RangeMap"
+	packedRangeStoreKind = "sparse.PackedRangeStore"
+)
+
+// snapshotSource returns a reader of a snapshot of kind
+type snapshotSource func(kind string) (*offheap.SnapshotReader, error)
+
+// fromReader reads a snapshot from r into memory
+func fromReader(r io.Reader) snapshotSource {
+	return func(kind string) (*offheap.SnapshotReader, error) {
+		return offheap.NewSnapshotReader(r, kind)
+	}
+}
+
+// fromFile maps a snapshot file read-only and shared, see offheap.OpenSnapshot
+func fromFile(path string) snapshotSource {
+	return func(kind string) (*offheap.SnapshotReader, error) {
+		return offheap.OpenSnapshot(path, kind)
+	}
+}
+
+// WriteTo writes a snapshot of the map to w, which can be loaded with LoadMap without sorting.
+// Values kept on Go heap, as in ArrayInterface, cannot be written.
+func (s *Map[K, V]) WriteTo(w io.Writer) (int64, error) {
+	sw := offheap.NewSnapshotWriter(mapKind)
+	if err := s.snapshot(sw); err != nil {
+		return 0, err
+	}
+	return sw.WriteTo(w)
+}
+
+// snapshot adds keys, values and deletion marks, if any, to a snapshot
+func (s *Map[K, V]) snapshot(w *offheap.SnapshotWriter) error {
+	offheap.AddArrayColumn(w, s.keys)
+	if err := s.values.snapshot(w); err != nil {
+		return err
+	}
+	if s.tombstones > 0 {
+		w.AddPackedColumn(s.deleted.PackedArray)
+	}
+	return nil
+}
+
+// LoadMap reads a map written by Map.WriteTo into off-heap memory mapped according to opts.
+// V must match the type of written values.
+func LoadMap[K Key, V any](r io.Reader, opts offheap.Options) (*Map[K, V], error) {
+	var noValue V
+	return loadMap[K](fromReader(r), opts, readArrayColumn[V], noValue)
+}
+
+// OpenMap maps a snapshot file written by Map.WriteTo read-only and shared, see offheap.OpenSnapshot.
+// The map is frozen, and serves lookups straight from page cache.
+func OpenMap[K Key, V any](path string, opts offheap.Options) (*Map[K, V], error) {
+	var noValue V
+	return loadMap[K](fromFile(path), opts, readArrayColumn[V], noValue)
+}
+
+func loadMap[K Key, V any](
+	src snapshotSource,
+	opts offheap.Options,
+	readValues func(*offheap.SnapshotReader, offheap.Options) (column[V], error),
+	noValue V,
+) (*Map[K, V], error) {
+	sr, err := src(mapKind)
+	if err != nil {
+		return nil, err
+	}
+	return readMap[K](sr, opts, readValues, noValue)
+}
+
+func readMap[K Key, V any](
+	r *offheap.SnapshotReader,
+	opts offheap.Options,
+	readValues func(*offheap.SnapshotReader, offheap.Options) (column[V], error),
+	noValue V,
+) (_ *Map[K, V], err error) {
+	opts = growthOptions(opts, DefaultGrow)
+
+	keys, err := offheap.ReadArrayColumn[K](r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := readValues(r, opts)
+	if err != nil {
+		keys.Dealloc()
+		return nil, err
+	}
+
+	s := &Map[K, V]{keys: keys, values: values, noValue: noValue}
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	if values.Len() != keys.Len() {
+		return nil, fmt.Errorf("%w: %d keys, but %d values", offheap.ErrSnapshot, keys.Len(), values.Len())
+	}
+
+	if r.Remaining() > 0 {
+		deleted, err := r.ReadPackedColumn(opts)
+		if err != nil {
+			return nil, err
+		}
+		s.deleted = &packedColumn{deleted}
+
+		if deleted.Width() != 1 || deleted.Len() != keys.Len() {
+			return nil, fmt.Errorf("%w: malformed deletion bitmap", offheap.ErrSnapshot)
+		}
+		s.tombstones = deleted.OnesCount(0, deleted.Len())
+		s.ranks = newRankIndex(deleted)
+	}
+
+	return s, nil
+}
+
+// LoadSparseArrayInt reads a map written by WriteTo, see LoadMap
+func LoadSparseArrayInt(r io.Reader, opts offheap.Options) (*ArrayInt, error) {
+	return loadSparseArrayInt(fromReader(r), opts)
+}
+
+// OpenSparseArrayInt maps a snapshot file written by WriteTo, see OpenMap
+func OpenSparseArrayInt(path string, opts offheap.Options) (*ArrayInt, error) {
+	return loadSparseArrayInt(fromFile(path), opts)
+}
+
+func loadSparseArrayInt(src snapshotSource, opts offheap.Options) (*ArrayInt, error) {
+	m, err := loadMap[ArrayUint64Key](src, opts, readArrayColumn[offheap.ArrayIntValue], NoValue)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayInt{m}, nil
+}
+
+// LoadSparseArrayUint16 reads a map written by WriteTo, see LoadMap
+func LoadSparseArrayUint16(r io.Reader, opts offheap.Options) (*ArrayUint16, error) {
+	return loadSparseArrayUint16(fromReader(r), opts)
+}
+
+// OpenSparseArrayUint16 maps a snapshot file written by WriteTo, see OpenMap
+func OpenSparseArrayUint16(path string, opts offheap.Options) (*ArrayUint16, error) {
+	return loadSparseArrayUint16(fromFile(path), opts)
+}
+
+func loadSparseArrayUint16(src snapshotSource, opts offheap.Options) (*ArrayUint16, error) {
+	m, err := loadMap[ArrayUint64Key](src, opts, readArrayColumn[offheap.ArrayUint16Value], ArrayUint16NoValue)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayUint16{m}, nil
+}
+
+// LoadArrayUint32Uint16 reads a map written by WriteTo, see LoadMap
+func LoadArrayUint32Uint16(r io.Reader, opts offheap.Options) (*ArrayUint32Uint16, error) {
+	return loadArrayUint32Uint16(fromReader(r), opts)
+}
+
+// OpenArrayUint32Uint16 maps a snapshot file written by WriteTo, see OpenMap
+func OpenArrayUint32Uint16(path string, opts offheap.Options) (*ArrayUint32Uint16, error) {
+	return loadArrayUint32Uint16(fromFile(path), opts)
+}
+
+func loadArrayUint32Uint16(src snapshotSource, opts offheap.Options) (*ArrayUint32Uint16, error) {
+	m, err := loadMap[ArrayUint32Key](src, opts, readArrayColumn[offheap.ArrayUint16Value], ArrayUint16NoValue)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayUint32Uint16{m}, nil
+}
+
+// LoadSparseArrayPacked reads a map written by WriteTo, with the width of values it was created with, see LoadMap
+func LoadSparseArrayPacked(r io.Reader, opts offheap.Options) (*ArrayPacked, error) {
+	return loadSparseArrayPacked(fromReader(r), opts)
+}
+
+// OpenSparseArrayPacked maps a snapshot file written by WriteTo, see OpenMap
+func OpenSparseArrayPacked(path string, opts offheap.Options) (*ArrayPacked, error) {
+	return loadSparseArrayPacked(fromFile(path), opts)
+}
+
+func loadSparseArrayPacked(src snapshotSource, opts offheap.Options) (*ArrayPacked, error) {
+	m, err := loadMap[ArrayUint64Key](src, opts, readPackedColumn, 0)
+	if err != nil {
+		return nil, err
+	}
+	m.noValue = m.values.(*packedColumn).Max()
+	return &ArrayPacked{m}, nil
+}
+
+// WriteTo writes a snapshot of the array to w, which can be loaded with LoadSparseArrayBytes without sorting
+func (s *ArrayBytes) WriteTo(w io.Writer) (int64, error) {
+	sw := offheap.NewSnapshotWriter(arrayBytesKind)
+	sw.AddArenaColumns(s.arena)
+	if err := s.refs.snapshot(sw); err != nil {
+		return 0, err
+	}
+	return sw.WriteTo(w)
+}
+
+// LoadSparseArrayBytes reads an array written by ArrayBytes.WriteTo into off-heap memory mapped according to opts
+func LoadSparseArrayBytes(r io.Reader, opts offheap.Options) (*ArrayBytes, error) {
+	return loadSparseArrayBytes(fromReader(r), opts)
+}
+
+// OpenSparseArrayBytes maps a snapshot file written by ArrayBytes.WriteTo read-only and shared,
+// see offheap.OpenSnapshot
+func OpenSparseArrayBytes(path string, opts offheap.Options) (*ArrayBytes, error) {
+	return loadSparseArrayBytes(fromFile(path), opts)
+}
+
+func loadSparseArrayBytes(src snapshotSource, opts offheap.Options) (*ArrayBytes, error) {
+	sr, err := src(arrayBytesKind)
+	if err != nil {
+		return nil, err
+	}
+
+	arena, err := sr.ReadArenaColumns(growthOptions(opts, DefaultGrow))
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := readMap[ArrayUint64Key](sr, opts, readArrayColumn[uint32], 0)
+	if err != nil {
+		arena.Dealloc()
+		return nil, err
+	}
+
+	s := &ArrayBytes{refs, arena}
+	for i := 0; i < refs.Size(); i++ {
+		if !refs.isDeleted(i) && int(refs.values.Get(i)) >= arena.Len() {
+			s.Close()
+			return nil, fmt.Errorf("%w: value reference %d is out of range", offheap.ErrSnapshot, refs.values.Get(i))
+		}
+	}
+	return s, nil
+}
+
+// WriteTo writes a snapshot of the map to w, which can be loaded with LoadRangeMap without sorting
+func (s *RangeMap[P]) WriteTo(w io.Writer) (int64, error) {
+	sw := offheap.NewSnapshotWriter(rangeMapKind)
+	s.snapshot(sw)
+	return sw.WriteTo(w)
+}
+
+func (s *RangeMap[P]) snapshot(sw *offheap.SnapshotWriter) {
+	offheap.AddArrayColumn(sw, s.from)
+	sw.AddRecordColumn(s.records)
+}
+
+// LoadRangeMap reads a map written by RangeMap.WriteTo into off-heap memory mapped according to opts.
+// P must match the type of written payloads.
+func LoadRangeMap[P any](r io.Reader, opts offheap.Options) (RangeMap[P], error) {
+	return loadRangeMap[P](fromReader(r), opts)
+}
+
+// OpenRangeMap maps a snapshot file written by RangeMap.WriteTo read-only and shared, see offheap.OpenSnapshot
+func OpenRangeMap[P any](path string, opts offheap.Options) (RangeMap[P], error) {
+	return loadRangeMap[P](fromFile(path), opts)
+}
+
+func loadRangeMap[P any](src snapshotSource, opts offheap.Options) (RangeMap[P], error) {
+	size, payload, err := fixedPayload[P]()
+	if err != nil {
+		return RangeMap[P]{}, err
+	}
+
+	sr, err := src(rangeMapKind)
+	if err != nil {
+		return RangeMap[P]{}, err
+	}
+	return readRangeMap(sr, growthOptions(opts, DefaultGrow), size, payload)
+}
+
+// readRangeMap reads columns written by RangeMap.snapshot, with records holding payloads of size bytes
+func readRangeMap[P any](
+	sr *offheap.SnapshotReader,
+	opts offheap.Options,
+	size int,
+	payload rangePayload[P],
+) (s RangeMap[P], err error) {
+	defer func() {
+		if err != nil {
+			s.Close()
+			s = RangeMap[P]{}
+		}
+	}()
+
+	stride, err := s.layout(size, payload)
+	if err != nil {
+		return
+	}
+
+	if s.from, err = offheap.ReadArrayColumn[uint64](sr, opts); err != nil {
+		return
+	}
+	if s.records, err = sr.ReadRecordColumn(stride, opts); err != nil {
+		return
+	}
+
+	if s.records.Len() != s.from.Len() {
+		err = fmt.Errorf("%w: %d ranges, but %d payloads", offheap.ErrSnapshot, s.from.Len(), s.records.Len())
+	}
+	return
+}
+
+// WriteTo writes a snapshot of the store to w, which can be loaded with LoadSparseRangeStore without sorting
+func (s *RangeStore) WriteTo(w io.Writer) (int64, error) {
+	return s.m.WriteTo(w)
+}
+
+// LoadSparseRangeStore reads a store written by RangeStore.WriteTo, see LoadRangeMap
+func LoadSparseRangeStore(r io.Reader, opts offheap.Options) (RangeStore, error) {
+	m, err := LoadRangeMap[rangeStoreValue](r, opts)
+	return RangeStore{m: m}, err
+}
+
+// OpenSparseRangeStore maps a snapshot file written by RangeStore.WriteTo, see OpenRangeMap
+func OpenSparseRangeStore(path string, opts offheap.Options) (RangeStore, error) {
+	m, err := OpenRangeMap[rangeStoreValue](path, opts)
+	return RangeStore{m: m}, err
+}
+
+// WriteTo writes a snapshot of the store to w, which can be loaded with LoadPackedRangeStore without sorting
+func (s *PackedRangeStore) WriteTo(w io.Writer) (int64, error) {
+	// An empty packed column records the width of values
+	width, err := offheap.NewPackedArrayE(s.width, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer width.Dealloc()
+
+	sw := offheap.NewSnapshotWriter(packedRangeStoreKind)
+	sw.AddPackedColumn(width)
+	s.m.snapshot(sw)
+	return sw.WriteTo(w)
+}
+
+// LoadPackedRangeStore reads a store written by PackedRangeStore.WriteTo into off-heap memory mapped
+// according to opts, with the width of values it was created with
+func LoadPackedRangeStore(r io.Reader, opts offheap.Options) (PackedRangeStore, error) {
+	return loadPackedRangeStore(fromReader(r), opts)
+}
+
+// OpenPackedRangeStore maps a snapshot file written by PackedRangeStore.WriteTo read-only and shared,
+// see offheap.OpenSnapshot
+func OpenPackedRangeStore(path string, opts offheap.Options) (PackedRangeStore, error) {
+	return loadPackedRangeStore(fromFile(path), opts)
+}
+
+func loadPackedRangeStore(src snapshotSource, opts offheap.Options) (PackedRangeStore, error) {
+	opts = growthOptions(opts, DefaultGrow)
+
+	sr, err := src(packedRangeStoreKind)
+	if err != nil {
+		return PackedRangeStore{}, err
+	}
+
+	width, err := sr.ReadPackedColumn(opts)
+	if err != nil {
+		return PackedRangeStore{}, err
+	}
+	payload := packedPayload{width.Width()}
+	width.Dealloc()
+
+	m, err := readRangeMap[[2]uint64](sr, opts, payload.size(), payload)
+	return PackedRangeStore{m: m, width: payload.width}, err
+}
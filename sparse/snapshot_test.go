@@ -0,0 +1,189 @@
+package sparse
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andy722/structures/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayUint16_Snapshot(t *testing.T) {
+	b := NewArrayUint16Builder1(16, DefaultGrow)
+	for _, k := range rand.Perm(100) {
+		b.Add(ArrayUint64Key(k), uint16(k*2))
+	}
+	s := b.Build()
+	defer s.Close()
+	s.Delete(50)
+
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	loaded, err := LoadSparseArrayUint16(bytes.NewReader(buf.Bytes()), offheap.Options{})
+	assert.NoError(t, err)
+	defer loaded.Close()
+
+	assert.Equal(t, 99, loaded.CountRange(0, 100))
+	for k := ArrayUint64Key(0); k < 100; k++ {
+		v, ok := loaded.Lookup(k)
+		assert.Equal(t, k != 50, ok)
+		if ok {
+			assert.Equal(t, uint16(k*2), v)
+		}
+	}
+
+	loaded.Add(200, 1)
+	assert.Equal(t, uint16(1), loaded.Get(200))
+
+	_, err = LoadSparseArrayInt(bytes.NewReader(buf.Bytes()), offheap.Options{})
+	assert.ErrorIs(t, err, offheap.ErrSnapshot)
+	_, err = LoadSparseRangeStore(bytes.NewReader(buf.Bytes()), offheap.Options{})
+	assert.ErrorIs(t, err, offheap.ErrSnapshot)
+
+	iface := NewSparseArray(1, DefaultGrow)
+	_, err = iface.WriteTo(&buf)
+	assert.Error(t, err)
+	iface.Close()
+}
+
+func TestArrayPacked_Snapshot(t *testing.T) {
+	s := NewSparseArrayPacked(5, 4, DefaultGrow)
+	defer s.Close()
+	s.Add(1, 31)
+	s.Add(2, 7)
+
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	loaded, err := LoadSparseArrayPacked(&buf, offheap.Options{})
+	assert.NoError(t, err)
+	defer loaded.Close()
+
+	assert.Equal(t, uint64(31), loaded.NoValue())
+	assert.Equal(t, uint64(31), loaded.Get(1))
+	assert.Equal(t, uint64(7), loaded.Get(2))
+	assert.Panics(t, func() { loaded.Add(3, 32) })
+}
+
+func TestArrayBytes_Snapshot(t *testing.T) {
+	b := NewArrayBytesBuilder1(4, DefaultGrow)
+	b.AddString(2, "two")
+	b.AddString(1, "one")
+	s := b.Build()
+	defer s.Close()
+
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	loaded, err := LoadSparseArrayBytes(&buf, offheap.Options{})
+	assert.NoError(t, err)
+	defer loaded.Close()
+
+	v, ok := loaded.GetString(2)
+	assert.True(t, ok)
+	assert.Equal(t, "two", v)
+
+	loaded.Add(3, []byte("three"))
+	v, _ = loaded.GetString(3)
+	assert.Equal(t, "three", v)
+}
+
+func TestRangeStore_Snapshot(t *testing.T) {
+	b := NewRangeStoreBuilder(4)
+	b.Add(10, 19, 1, 2)
+	b.Add(0, 9, 3, 4)
+	s := b.Build()
+	defer s.Close()
+
+	var buf bytes.Buffer
+	_, err := s.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	loaded, err := LoadSparseRangeStore(&buf, offheap.Options{})
+	assert.NoError(t, err)
+	defer loaded.Close()
+
+	v1, v2, ok := loaded.Get(15)
+	assert.True(t, ok)
+	assert.Equal(t, []uint16{1, 2}, []uint16{v1, v2})
+
+	pb := NewPackedRangeStoreBuilder(20, 2)
+	pb.Add(0, 9, 1<<20-1, 5)
+	ps := pb.Build()
+	defer ps.Close()
+
+	buf.Reset()
+	_, err = ps.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	pl, err := LoadPackedRangeStore(&buf, offheap.Options{})
+	assert.NoError(t, err)
+	defer pl.Close()
+
+	p1, p2, ok := pl.Get(9)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{1<<20 - 1, 5}, []uint64{p1, p2})
+}
+
+func TestOpenSnapshot(t *testing.T) {
+	b := NewArrayUint16Builder1(16, DefaultGrow)
+	for k := 0; k < 100; k++ {
+		b.Add(ArrayUint64Key(k), uint16(k*2))
+	}
+	s := b.Build()
+	defer s.Close()
+	s.Delete(50)
+
+	path := filepath.Join(t.TempDir(), "map")
+	writeSnapshot(t, path, s)
+
+	opened, err := OpenSparseArrayUint16(path, offheap.Options{})
+	assert.NoError(t, err)
+	defer opened.Close()
+
+	assert.True(t, opened.Frozen())
+	assert.Equal(t, 99, opened.CountRange(0, 100))
+	_, ok := opened.Lookup(50)
+	assert.False(t, ok)
+	assert.Equal(t, uint16(198), opened.Get(99))
+	assert.PanicsWithValue(t, offheap.ErrFrozen, func() { opened.Add(200, 1) })
+
+	_, err = OpenSparseRangeStore(path, offheap.Options{})
+	assert.ErrorIs(t, err, offheap.ErrSnapshot)
+
+	rb := NewRangeStoreBuilder(4)
+	rb.Add(10, 19, 1, 2)
+	rb.Add(0, 9, 3, 4)
+	rs := rb.Build()
+	defer rs.Close()
+
+	path = filepath.Join(t.TempDir(), "ranges")
+	writeSnapshot(t, path, &rs)
+
+	ropened, err := OpenSparseRangeStore(path, offheap.Options{})
+	assert.NoError(t, err)
+	defer ropened.Close()
+
+	assert.True(t, ropened.Frozen())
+	v1, v2, ok := ropened.Get(5)
+	assert.True(t, ok)
+	assert.Equal(t, []uint16{3, 4}, []uint16{v1, v2})
+}
+
+func writeSnapshot(t *testing.T, path string, s io.WriterTo) {
+	f, err := os.Create(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = s.WriteTo(f)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
@@ -0,0 +1,252 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/offheap"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// radixBits is the maximum width of digits sorted by a single pass of radix sort, with up to 1<<radixBits buckets
+const radixBits = 11
+
+// radixThreshold is the number of entries below which comparison sort is used, as radix sort buffers
+// are not worth mapping
+const radixThreshold = 1 << 12
+
+// sortByKeys stably sorts entries by keys, reordering other columns with permute.
+// Large columns are sorted by LSD radix sort, small ones with sorter, which must swap entries in all columns.
+// Sorter is also used if radix sort buffers cannot be allocated. Only order is kept when permute is called,
+// so columns copied there one at a time do not add up.
+func sortByKeys[K Key](keys **offheap.Array[K], sorter sort.Interface, permute func(order *offheap.Array[uint32])) {
+	n := (*keys).Len()
+	if n < radixThreshold || uint64(n) > math.MaxUint32 {
+		sort.Stable(sorter)
+		return
+	}
+
+	order, err := radixSort(keys)
+	if err != nil {
+		sort.Stable(sorter)
+		return
+	}
+	if order == nil {
+		return
+	}
+
+	permute(order)
+	order.Dealloc()
+}
+
+// radixSort stably sorts keys, returning original indices of sorted keys, or nil if keys are equal.
+// Sorted keys replace *keys, which is scattered back and forth with a single spare array.
+// Digits only cover bits differing between keys, which are split into passes of up to radixBits.
+func radixSort[K Key](keys **offheap.Array[K]) (_ *offheap.Array[uint32], err error) {
+	src := *keys
+	n := src.Len()
+
+	first := uint64(src.Get(0))
+	var diff uint64
+	for i := 1; i < n; i++ {
+		diff |= uint64(src.Get(i)) ^ first
+	}
+	if diff == 0 {
+		return nil, nil
+	}
+
+	low := bits.TrailingZeros64(diff)
+	span := bits.Len64(diff) - low
+	digits := (span + radixBits - 1) / radixBits
+	width := (span + digits - 1) / digits
+	mask := uint64(1)<<width - 1
+
+	// Histograms of all digits are collected in a single pass, as they don't depend on order
+	counts := make([][]int, digits)
+	for d := range counts {
+		counts[d] = make([]int, 1<<width)
+	}
+	for i := 0; i < n; i++ {
+		key := uint64(src.Get(i)) >> low
+		for d := range counts {
+			counts[d][key>>(d*width)&mask]++
+		}
+	}
+
+	var passes []int
+	for d := range counts {
+		if counts[d][first>>low>>(d*width)&mask] != n {
+			passes = append(passes, d)
+		}
+	}
+
+	// The spare array may replace keys, so it keeps their options and capacity
+	var spare *offheap.Array[K]
+	var orders [2]*offheap.Array[uint32]
+	defer func() {
+		if spare != nil {
+			spare.Dealloc()
+		}
+		for i := range orders {
+			if orders[i] != nil && (err != nil || i != (len(passes)-1)%2) {
+				orders[i].Dealloc()
+			}
+		}
+	}()
+
+	if spare, err = offheap.NewArrayWithOptions[K](src.Cap(), src.Options()); err != nil {
+		return nil, err
+	}
+	extend(spare, n)
+
+	opts := src.Options()
+	opts.Growth = offheap.GrowthPolicy{}
+	for i := 0; i < 2 && i < len(passes); i++ {
+		if orders[i], err = offheap.NewArrayWithOptions[uint32](n, opts); err != nil {
+			return nil, err
+		}
+		extend(orders[i], n)
+	}
+
+	dstKeys := spare
+	srcOrder := (*offheap.Array[uint32])(nil)
+	offsets := make([]int, 1<<width)
+	for p, d := range passes {
+		dstOrder := orders[p%2]
+
+		for b, sum := 0, 0; b < len(offsets); b++ {
+			offsets[b] = sum
+			sum += counts[d][b]
+		}
+
+		shift := low + d*width
+		for i := 0; i < n; i++ {
+			key := src.Get(i)
+			idx := uint32(i)
+			if srcOrder != nil {
+				idx = srcOrder.Get(i)
+			}
+
+			b := uint64(key) >> shift & mask
+			dstKeys.Set(offsets[b], key)
+			dstOrder.Set(offsets[b], idx)
+			offsets[b]++
+		}
+
+		src, dstKeys = dstKeys, src
+		srcOrder = dstOrder
+	}
+
+	if src == spare {
+		(*keys).Dealloc()
+		*keys, spare = spare, nil
+	}
+	return srcOrder, nil
+}
+
+// extend appends n zero elements to a
+func extend[T any](a *offheap.Array[T], n int) {
+	zeros := make([]T, 4096)
+	for ; n > len(zeros); n -= len(zeros) {
+		a.AppendSlice(zeros)
+	}
+	a.AppendSlice(zeros[:n])
+}
+
+// permuteArray reorders *a so that element order[i] moves to index i. Elements are gathered into a new array
+// replacing *a, as independent loads are much faster than moves along cycles of the permutation,
+// which are used if the new array cannot be allocated.
+func permuteArray[T any](a **offheap.Array[T], order *offheap.Array[uint32]) {
+	src := *a
+	dst, err := offheap.NewArrayWithOptions[T](src.Cap(), src.Options())
+	if err != nil {
+		permuteInPlace[T](src, order)
+		return
+	}
+
+	for i := 0; i < order.Len(); i++ {
+		dst.Append(src.Get(int(order.Get(i))))
+	}
+	src.Dealloc()
+	*a = dst
+}
+
+// permutePacked is like permuteArray for packed arrays
+func permutePacked(a **offheap.PackedArray, order *offheap.Array[uint32]) {
+	src := *a
+	dst, err := offheap.NewPackedArrayWithOptions(src.Width(), src.Cap(), src.Options())
+	if err != nil {
+		permuteInPlace[uint64](src, order)
+		return
+	}
+
+	for i := 0; i < order.Len(); i++ {
+		dst.Append(src.Get(int(order.Get(i))))
+	}
+	src.Dealloc()
+	*a = dst
+}
+
+// permuteRecords is like permuteArray for record arrays
+func permuteRecords(a **offheap.RecordArray, order *offheap.Array[uint32]) {
+	src := *a
+	dst, err := offheap.NewRecordArrayWithOptions(src.Stride(), src.Cap(), src.Options())
+	if err != nil {
+		swapInPlace(order, src.Swap)
+		return
+	}
+
+	for i := 0; i < order.Len(); i++ {
+		dst.Append(src.Get(int(order.Get(i))))
+	}
+	src.Dealloc()
+	*a = dst
+}
+
+// permuted is an array reordered by permuteInPlace
+type permuted[T any] interface {
+	Get(i int) T
+	Set(i int, v T)
+}
+
+// permuteInPlace reorders elements so that element order[i] moves to index i.
+// Elements are moved along cycles of the permutation, holding only the first element of a cycle aside.
+func permuteInPlace[T any](a permuted[T], order *offheap.Array[uint32]) {
+	done := make([]uint64, (order.Len()+63)/64)
+	for i := 0; i < order.Len(); i++ {
+		if done[i/64]&(1<<(i%64)) != 0 {
+			continue
+		}
+
+		v, j := a.Get(i), i
+		for {
+			done[j/64] |= 1 << (j % 64)
+
+			k := int(order.Get(j))
+			if k == i {
+				break
+			}
+
+			a.Set(j, a.Get(k))
+			j = k
+		}
+		a.Set(j, v)
+	}
+}
+
+// swapInPlace is like permuteInPlace for arrays whose elements cannot be held aside, moving them with swap
+func swapInPlace(order *offheap.Array[uint32], swap func(i, j int)) {
+	done := make([]uint64, (order.Len()+63)/64)
+	for i := 0; i < order.Len(); i++ {
+		for j := i; done[j/64]&(1<<(j%64)) == 0; {
+			done[j/64] |= 1 << (j % 64)
+
+			k := int(order.Get(j))
+			if k == i {
+				break
+			}
+
+			swap(j, k)
+			j = k
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package sparse
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/andy722/structures/offheap"
+	"github.com/stretchr/testify/assert"
+)
+
+func BenchmarkSparseArrayBuilder_Build(b *testing.B) {
+	items := pseudoRandomArray(1 << 20)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		s := NewArrayIntBuilder(len(items), DefaultGrow)
+		for j, v := range items {
+			s.Add(ArrayUint64Key(v)<<20, j)
+		}
+		b.StartTimer()
+
+		s.Build().Close()
+	}
+}
+
+func TestBuilder_RadixSort(t *testing.T) {
+	const n = 3 * radixThreshold
+	items := pseudoRandomArray(n)
+
+	b := NewArrayBytesBuilder1(16, DefaultGrow)
+	ib := NewArrayIntBuilder(16, DefaultGrow)
+	ab := NewArrayInterfaceBuilder1(16, DefaultGrow)
+	pb := NewPackedRangeStoreBuilder(20, 16)
+	rb := NewRangeMapBuilder[uint32](16)
+	for _, v := range items {
+		key := ArrayUint64Key(v) << 40 // Leaves low bytes equal in all keys
+		b.AddString(key, fmt.Sprint(v))
+		ib.Add(key, v)
+		ab.Add(key, v)
+		pb.Add(ArrayUint64Key(v)*10, ArrayUint64Key(v)*10+9, uint64(v), uint64(v)+1)
+		rb.Add(ArrayUint64Key(v)*10, ArrayUint64Key(v)*10+9, uint32(v))
+	}
+	b.Delete(7 << 40) // Sorts before Build
+
+	s := b.Build()
+	defer s.Close()
+	is := ib.Build()
+	defer is.Close()
+	as := ab.Build()
+	defer as.Close()
+	ps := pb.Build()
+	defer ps.Close()
+	rs := rb.Build()
+	defer rs.Close()
+
+	assert.Equal(t, n-1, s.CountRange(0, math.MaxUint64))
+	prev := -1
+	s.Ascend(func(key ArrayUint64Key, val []byte) bool {
+		v := int(key >> 40)
+		assert.Less(t, prev, v)
+		assert.Equal(t, fmt.Sprint(v), string(val))
+		prev = v
+		return true
+	})
+	assert.Equal(t, n-1, prev)
+
+	for v := 0; v < n; v++ {
+		assert.Equal(t, v, is.Get(ArrayUint64Key(v)<<40))
+		assert.Equal(t, v, as.Get(ArrayUint64Key(v)<<40))
+
+		v1, v2, ok := ps.Get(ArrayUint64Key(v)*10 + 5)
+		assert.True(t, ok)
+		assert.Equal(t, []uint64{uint64(v), uint64(v) + 1}, []uint64{v1, v2})
+
+		p, ok := rs.Get(ArrayUint64Key(v)*10 + 5)
+		assert.True(t, ok)
+		assert.Equal(t, uint32(v), p)
+	}
+}
+
+func TestRadixSort(t *testing.T) {
+	keys := offheap.NewArray[uint32](radixThreshold)
+	for i := 0; i < radixThreshold; i++ {
+		keys.Append(uint32(i % 3 << 16))
+	}
+	original := keys
+
+	order, err := radixSort(&keys)
+	assert.NoError(t, err)
+	defer keys.Dealloc()
+	defer order.Dealloc()
+
+	// A single pass leaves sorted keys in the spare array
+	assert.NotSame(t, original, keys)
+
+	// Equal keys keep their order
+	third := (radixThreshold + 2) / 3
+	assert.Equal(t, uint32(0), order.Get(0))
+	assert.Equal(t, uint32(3), order.Get(1))
+	assert.Equal(t, uint32(1), order.Get(third))
+	assert.Equal(t, uint32(0), keys.Get(third-1))
+	assert.Equal(t, uint32(1<<16), keys.Get(third))
+
+	// Swapping and moving in place yield the same order as gathering
+	a := offheap.NewArray[int](keys.Len())
+	defer a.Dealloc()
+	b := offheap.NewArray[int](keys.Len())
+	for i := 0; i < keys.Len(); i++ {
+		a.Append(i)
+		b.Append(i)
+	}
+	swapInPlace(order, a.Swap)
+	permuteArray(&b, order)
+	defer b.Dealloc()
+	for i := 0; i < a.Len(); i++ {
+		assert.Equal(t, int(order.Get(i)), a.Get(i))
+		assert.Equal(t, int(order.Get(i)), b.Get(i))
+	}
+	for i := 0; i < a.Len(); i++ {
+		a.Set(i, i)
+	}
+	permuteInPlace[int](a, order)
+	for i := 0; i < a.Len(); i++ {
+		assert.Equal(t, int(order.Get(i)), a.Get(i))
+	}
+
+	keys.Fill(0, keys.Len(), 5)
+	order, err = radixSort(&keys)
+	assert.NoError(t, err)
+	assert.Nil(t, order)
+}
+
+func TestBuilder_SortStable(t *testing.T) {
+	// The first value added for a duplicate key wins, whichever sort is used
+	for _, n := range []int{radixThreshold / 2, 2 * radixThreshold} {
+		b := NewArrayIntBuilder(16, DefaultGrow)
+		for i, v := range pseudoRandomArray(n) {
+			b.Add(ArrayUint64Key(v%(n/2)), i)
+		}
+
+		s := b.Build()
+		first := make(map[int]int)
+		for i, v := range pseudoRandomArray(n) {
+			if _, ok := first[v%(n/2)]; !ok {
+				first[v%(n/2)] = i
+			}
+		}
+		for k, i := range first {
+			assert.Equal(t, i, s.Get(ArrayUint64Key(k)), "n=%d key=%d", n, k)
+		}
+		s.Close()
+	}
+}
+
+func TestRadixSort_Passes(t *testing.T) {
+	// Bits differing between keys span several digits, sorted by an even number of passes
+	items := pseudoRandomArray(2 * radixThreshold)
+	keys := offheap.NewArray[uint64](len(items))
+	for _, v := range items {
+		keys.Append(uint64(v)<<(radixBits+7) | 1<<63)
+	}
+	original := keys
+
+	order, err := radixSort(&keys)
+	assert.NoError(t, err)
+	defer keys.Dealloc()
+	defer order.Dealloc()
+
+	assert.Same(t, original, keys)
+	for i := 0; i < keys.Len(); i++ {
+		assert.Equal(t, uint64(i)<<(radixBits+7)|1<<63, keys.Get(i))
+		assert.Equal(t, i, items[order.Get(i)])
+	}
+}
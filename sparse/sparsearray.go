@@ -1,160 +1,81 @@
 package sparse
 
-import (
-	"github.com/andy722/structures/offheap"
-	"sort"
-)
+import "github.com/andy722/structures/offheap"
 
 const DefaultPreallocate = 17_000_000
 const DefaultGrow = 1.25
 
-// ArrayInterface provides an off-heap map with numeric keys, internally represented as sparse array
+// ArrayInterface provides an off-heap map with numeric keys, internally represented as sparse array, see Map
 type ArrayInterface struct {
-	arrayUint64
-
-	values *offheap.ArrayInterface
+	*Map[ArrayUint64Key, interface{}]
 }
 
 func NewSparseArray(preallocate int, grow float64) *ArrayInterface {
-	return &ArrayInterface{
-		arrayUint64{
-			preallocate,
-			grow,
-			offheap.NewArrayUint64(preallocate),
-		},
-		offheap.NewArrayInterface(preallocate),
-	}
-}
-
-func (s *ArrayInterface) Close() {
-	s.arrayUint64.Close()
-	s.values.Dealloc()
-}
-
-func (s *ArrayInterface) Add(key ArrayUint64Key, val interface{}) {
-	i := s.idx(key)
-	if i < s.keys.Len() && s.keys.Get(i) == key {
-		s.values.Set(i, val)
-		return
-	}
-
-	s.growBackingArraysIfNeeded()
-
-	s.keys.Insert(i, key)
-	s.values.Insert(i, val)
-}
-
-func (s *ArrayInterface) Get(key ArrayUint64Key) interface{} {
-	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key {
-		return s.values.Get(i)
-	}
-	return nil
-}
-
-func (s *ArrayInterface) Delete(key ArrayUint64Key) (prev interface{}) {
-	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key {
-		prev = s.values.Get(i)
-		s.values.Set(i, nil)
-	}
-	return
-}
-
-func (s *ArrayInterface) growBackingArraysIfNeeded() {
-	size := s.Size()
-	if size < s.cap() {
-		return
+	s, err := NewSparseArrayE(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
-
-	newSize := int(s.grow * float64(size))
-
-	s.keys = s.keys.Grow(newSize)
-	s.values = s.values.Grow(newSize)
+	return s
 }
 
-func (s *ArrayInterface) cleanup() {
-	for i := s.Size() - 1; i >= 0; i-- {
-		if s.values.Get(i) == nil {
-			s.keys.Remove(i)
-			s.values.Remove(i)
-		}
-	}
+// NewSparseArrayE is like NewSparseArray, but returns an error if off-heap memory cannot be allocated
+func NewSparseArrayE(preallocate int, grow float64) (*ArrayInterface, error) {
+	return NewSparseArrayWithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (s *ArrayInterface) shrink() {
-	if size := s.Size(); size < s.cap() {
-		s.keys = s.keys.TrimToSize()
-		s.values = s.values.TrimToSize()
+// NewSparseArrayWithOptions is like NewSparseArrayE, with backing arrays mapped according to opts
+func NewSparseArrayWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayInterface, error) {
+	m, err := newMap[ArrayUint64Key](preallocate, grow, opts, newInterfaceColumn, nil)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayInterface{m}, nil
 }
 
 type ArrayInterfaceBuilder struct {
-	s             *ArrayInterface
-	shouldSort    bool // Marks as containing non-sorted data, need to sort prior to lookups
-	shouldCleanup bool // Marks as containing gaps, i.e. deleted entries
+	*Builder[ArrayUint64Key, interface{}]
 }
 
+//goland:noinspection GoUnusedExportedFunction
 func NewArrayInterfaceBuilder() *ArrayInterfaceBuilder {
 	return NewArrayInterfaceBuilder1(DefaultPreallocate, DefaultGrow)
 }
 
+func NewArrayInterfaceBuilderE() (*ArrayInterfaceBuilder, error) {
+	return NewArrayInterfaceBuilder1E(DefaultPreallocate, DefaultGrow)
+}
+
 func NewArrayInterfaceBuilder1(preallocate int, grow float64) *ArrayInterfaceBuilder {
-	return &ArrayInterfaceBuilder{
-		s: NewSparseArray(preallocate, grow),
+	b, err := NewArrayInterfaceBuilder1E(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
+	return b
 }
 
-func (b *ArrayInterfaceBuilder) Add(key ArrayUint64Key, value interface{}) {
-	b.shouldSort = true
-
-	b.s.growBackingArraysIfNeeded()
-
-	b.s.keys.Append(key)
-	b.s.values.Append(value)
+func NewArrayInterfaceBuilder1E(preallocate int, grow float64) (*ArrayInterfaceBuilder, error) {
+	return NewArrayInterfaceBuilderWithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (b *ArrayInterfaceBuilder) Delete(key ArrayUint64Key) {
-	if b.shouldSort {
-		b.sort()
-	}
-
-	if b.s.Delete(key) != nil {
-		b.shouldCleanup = true
+// NewArrayInterfaceBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewArrayInterfaceBuilderWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayInterfaceBuilder, error) {
+	s, err := NewSparseArrayWithOptions(preallocate, grow, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayInterfaceBuilder{&Builder[ArrayUint64Key, interface{}]{s: s.Map}}, nil
 }
 
 func (b *ArrayInterfaceBuilder) Build() *ArrayInterface {
-	if b.shouldCleanup {
-		b.s.cleanup()
-		b.shouldSort = true
-		b.shouldCleanup = false
-	}
-
-	if b.shouldSort {
-		b.sort()
-	}
-
-	b.s.shrink()
-
-	return b.s
-}
-
-func (b *ArrayInterfaceBuilder) sort() {
-	sort.Sort(sparseArraySorter(func() *ArrayInterface { return b.s }))
-	b.shouldSort = false
+	return &ArrayInterface{b.Builder.Build()}
 }
 
-type sparseArraySorter func() *ArrayInterface
-
-func (s sparseArraySorter) Len() int {
-	return s().Size()
-}
-
-func (s sparseArraySorter) Less(i, j int) bool {
-	keys := s().keys
-	return keys.Get(i) < keys.Get(j)
-}
-
-func (s sparseArraySorter) Swap(i, j int) {
-	s().keys.Swap(i, j)
-	s().values.Swap(i, j)
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *ArrayInterfaceBuilder) BuildE() (*ArrayInterface, error) {
+	m, err := b.Builder.BuildE()
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayInterface{m}, nil
 }
@@ -0,0 +1,290 @@
+package sparse
+
+import (
+	"github.com/andy722/structures/offheap"
+	"math"
+)
+
+// bytesPerValue is the expected average value size, used to preallocate ArrayBytes arena
+const bytesPerValue = 16
+
+// ArrayBytes provides an off-heap map with numeric keys and byte string values, internally represented
+// as sparse array of references to values kept in an offheap.BytesArena.
+// Values can only be appended to the arena, so replaced and deleted ones keep taking space until Compact is called
+// or the array is rebuilt with ArrayBytesBuilder.
+type ArrayBytes struct {
+	refs  *Map[ArrayUint64Key, uint32] // Index of a value in arena
+	arena *offheap.BytesArena
+}
+
+func NewSparseArrayBytes(preallocate int, grow float64) *ArrayBytes {
+	s, err := NewSparseArrayBytesE(preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewSparseArrayBytesE is like NewSparseArrayBytes, but returns an error if off-heap memory cannot be allocated
+func NewSparseArrayBytesE(preallocate int, grow float64) (*ArrayBytes, error) {
+	return NewSparseArrayBytesWithOptions(preallocate, grow, offheap.Options{})
+}
+
+// NewSparseArrayBytesWithOptions is like NewSparseArrayBytesE, with backing arrays mapped according to opts
+func NewSparseArrayBytesWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayBytes, error) {
+	refs, err := newMap[ArrayUint64Key](preallocate, grow, opts, newArrayColumn[uint32], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	arena, err := offheap.NewBytesArenaWithOptions(preallocate, preallocate*bytesPerValue, growthOptions(opts, grow))
+	if err != nil {
+		refs.Close()
+		return nil, err
+	}
+
+	return &ArrayBytes{refs, arena}, nil
+}
+
+func (s *ArrayBytes) Size() int {
+	return s.refs.Size()
+}
+
+func (s *ArrayBytes) Close() {
+	s.refs.Close()
+	s.arena.Dealloc()
+}
+
+// Freeze makes the array read-only, so that Add and Delete panic with offheap.ErrFrozen, see offheap.Array.Freeze
+func (s *ArrayBytes) Freeze() error {
+	if err := s.arena.Freeze(); err != nil {
+		return err
+	}
+	return s.refs.Freeze()
+}
+
+// Frozen tells if the array was made read-only with Freeze
+func (s *ArrayBytes) Frozen() bool {
+	return s.refs.Frozen()
+}
+
+func (s *ArrayBytes) Add(key ArrayUint64Key, val []byte) {
+	if err := s.AddE(key, val); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown.
+// A replaced value is not reclaimed until Compact is called.
+func (s *ArrayBytes) AddE(key ArrayUint64Key, val []byte) error {
+	if s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	ref, err := s.store(val)
+	if err != nil {
+		return err
+	}
+	return s.refs.AddE(key, ref)
+}
+
+// Get returns a value stored for key. The returned slice points into off-heap memory, so it must not be modified,
+// and is only valid until the array is changed or closed.
+func (s *ArrayBytes) Get(key ArrayUint64Key) (val []byte, exists bool) {
+	if ref, ok := s.refs.Lookup(key); ok {
+		return s.arena.Get(int(ref)), true
+	}
+	return nil, false
+}
+
+// GetString is like Get, but returns a copy of the value
+func (s *ArrayBytes) GetString(key ArrayUint64Key) (val string, exists bool) {
+	b, exists := s.Get(key)
+	return string(b), exists
+}
+
+// Delete removes a value stored for key, returning it, see Get
+func (s *ArrayBytes) Delete(key ArrayUint64Key) (prev []byte, exists bool) {
+	prev, exists, err := s.DeleteE(key)
+	if err != nil {
+		panic(err)
+	}
+	return prev, exists
+}
+
+// DeleteE is like Delete, but returns an error if the bitmap of deleted entries cannot be allocated
+func (s *ArrayBytes) DeleteE(key ArrayUint64Key) (prev []byte, exists bool, err error) {
+	ref, ok, err := s.refs.remove(key)
+	if ok {
+		return s.arena.Get(int(ref)), true, err
+	}
+	return nil, false, err
+}
+
+// store appends val to arena, returning its reference
+func (s *ArrayBytes) store(val []byte) (uint32, error) {
+	if uint64(s.arena.Len()) > math.MaxUint32 {
+		panic("sparse: too many values in ArrayBytes")
+	}
+
+	ref, err := s.arena.AppendE(val)
+	return uint32(ref), err
+}
+
+// Compact reclaims space taken by replaced and deleted values. Values returned by Get before are invalidated.
+func (s *ArrayBytes) Compact() error {
+	if s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	if err := s.compactArena(); err != nil {
+		return err
+	}
+	return s.shrinkArena()
+}
+
+// compactArena drops values which are no longer referenced, i.e. deleted or replaced,
+// storing the remaining ones in order of keys
+func (s *ArrayBytes) compactArena() error {
+	refs := s.refs.values
+	if s.arena.Len() == refs.Len()-s.refs.tombstones {
+		return nil
+	}
+
+	var bytes int
+	for i := 0; i < refs.Len(); i++ {
+		if !s.refs.isDeleted(i) {
+			bytes += len(s.arena.Get(int(refs.Get(i))))
+		}
+	}
+
+	arena, err := offheap.NewBytesArenaWithOptions(refs.Len(), bytes, s.arena.Options())
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < refs.Len(); i++ {
+		if !s.refs.isDeleted(i) {
+			if _, err := arena.AppendE(s.arena.Get(int(refs.Get(i)))); err != nil {
+				arena.Dealloc()
+				return err
+			}
+		}
+	}
+
+	// Values were stored in order of keys, so references are sequential
+	var ref uint32
+	for i := 0; i < refs.Len(); i++ {
+		if !s.refs.isDeleted(i) {
+			refs.Set(i, ref)
+			ref++
+		}
+	}
+
+	s.arena.Dealloc()
+	s.arena = arena
+	return nil
+}
+
+func (s *ArrayBytes) shrinkArena() error {
+	arena, err := s.arena.TrimToSizeE()
+	if err != nil {
+		return err
+	}
+	s.arena = arena
+	return nil
+}
+
+type ArrayBytesBuilder struct {
+	b *Builder[ArrayUint64Key, uint32] // Sorts references along with keys
+	s *ArrayBytes
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewArrayBytesBuilder() *ArrayBytesBuilder {
+	return NewArrayBytesBuilder1(DefaultPreallocate, DefaultGrow)
+}
+
+func NewArrayBytesBuilderE() (*ArrayBytesBuilder, error) {
+	return NewArrayBytesBuilder1E(DefaultPreallocate, DefaultGrow)
+}
+
+func NewArrayBytesBuilder1(preallocate int, grow float64) *ArrayBytesBuilder {
+	b, err := NewArrayBytesBuilder1E(preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func NewArrayBytesBuilder1E(preallocate int, grow float64) (*ArrayBytesBuilder, error) {
+	return NewArrayBytesBuilderWithOptions(preallocate, grow, offheap.Options{})
+}
+
+// NewArrayBytesBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewArrayBytesBuilderWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayBytesBuilder, error) {
+	s, err := NewSparseArrayBytesWithOptions(preallocate, grow, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayBytesBuilder{&Builder[ArrayUint64Key, uint32]{s: s.refs}, s}, nil
+}
+
+func (b *ArrayBytesBuilder) Add(key ArrayUint64Key, value []byte) {
+	if err := b.AddE(key, value); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (b *ArrayBytesBuilder) AddE(key ArrayUint64Key, value []byte) error {
+	if b.s.Frozen() {
+		return offheap.ErrFrozen
+	}
+
+	ref, err := b.s.store(value)
+	if err != nil {
+		return err
+	}
+	return b.b.AddE(key, ref)
+}
+
+// AddString is like Add, but stores s
+func (b *ArrayBytesBuilder) AddString(key ArrayUint64Key, s string) {
+	b.Add(key, []byte(s))
+}
+
+func (b *ArrayBytesBuilder) Delete(key ArrayUint64Key) {
+	b.b.Delete(key)
+}
+
+// DeleteE is like Delete, but returns an error if the bitmap of deleted entries cannot be allocated
+func (b *ArrayBytesBuilder) DeleteE(key ArrayUint64Key) error {
+	return b.b.DeleteE(key)
+}
+
+func (b *ArrayBytesBuilder) Build() *ArrayBytes {
+	s, err := b.BuildE()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *ArrayBytesBuilder) BuildE() (*ArrayBytes, error) {
+	if _, err := b.b.BuildE(); err != nil {
+		return nil, err
+	}
+
+	if err := b.s.compactArena(); err != nil {
+		return nil, err
+	}
+
+	if err := b.s.shrinkArena(); err != nil {
+		return nil, err
+	}
+
+	return b.s, nil
+}
@@ -0,0 +1,136 @@
+package sparse
+
+import (
+	"fmt"
+	"github.com/andy722/structures/offheap"
+)
+
+// ArrayPacked provides an off-heap map with numeric keys and values of a fixed bit width,
+// internally represented as sparse array with bit-packed values. The largest value of the width is returned
+// by Get for missing keys, see NoValue.
+type ArrayPacked struct {
+	*Map[ArrayUint64Key, uint64]
+}
+
+func NewSparseArrayPacked(width uint, preallocate int, grow float64) *ArrayPacked {
+	s, err := NewSparseArrayPackedE(width, preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewSparseArrayPackedE is like NewSparseArrayPacked, but returns an error if off-heap memory cannot be allocated
+func NewSparseArrayPackedE(width uint, preallocate int, grow float64) (*ArrayPacked, error) {
+	return NewSparseArrayPackedWithOptions(width, preallocate, grow, offheap.Options{})
+}
+
+// NewSparseArrayPackedWithOptions is like NewSparseArrayPackedE, with backing arrays mapped according to opts
+func NewSparseArrayPackedWithOptions(width uint, preallocate int, grow float64, opts offheap.Options) (*ArrayPacked, error) {
+	if width < 1 || width > 64 {
+		return nil, fmt.Errorf("sparse: packed width %d is not in range [1, 64]", width)
+	}
+
+	noValue := ^uint64(0) >> (64 - width)
+	m, err := newMap[ArrayUint64Key](preallocate, grow, opts, newPackedColumn(width), noValue)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayPacked{m}, nil
+}
+
+// Add maps key to val. Panics if val does not fit in the width.
+func (s *ArrayPacked) Add(key ArrayUint64Key, val uint64) {
+	if err := s.AddE(key, val); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (s *ArrayPacked) AddE(key ArrayUint64Key, val uint64) error {
+	checkPacked(s.Map, val)
+	return s.Map.AddE(key, val)
+}
+
+// checkPacked panics if v does not fit in the width of values
+func checkPacked(s *Map[ArrayUint64Key, uint64], v uint64) {
+	if v > s.NoValue() {
+		panic(fmt.Sprintf("sparse: value %d does not fit in %d bits", v, s.values.(*packedColumn).Width()))
+	}
+}
+
+type ArrayPackedBuilder struct {
+	*Builder[ArrayUint64Key, uint64]
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewArrayPackedBuilder(width uint) *ArrayPackedBuilder {
+	return NewArrayPackedBuilder1(width, DefaultPreallocate, DefaultGrow)
+}
+
+func NewArrayPackedBuilderE(width uint) (*ArrayPackedBuilder, error) {
+	return NewArrayPackedBuilder1E(width, DefaultPreallocate, DefaultGrow)
+}
+
+func NewArrayPackedBuilder1(width uint, preallocate int, grow float64) *ArrayPackedBuilder {
+	b, err := NewArrayPackedBuilder1E(width, preallocate, grow)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func NewArrayPackedBuilder1E(width uint, preallocate int, grow float64) (*ArrayPackedBuilder, error) {
+	return NewArrayPackedBuilderWithOptions(width, preallocate, grow, offheap.Options{})
+}
+
+// NewArrayPackedBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewArrayPackedBuilderWithOptions(width uint, preallocate int, grow float64, opts offheap.Options) (*ArrayPackedBuilder, error) {
+	s, err := NewSparseArrayPackedWithOptions(width, preallocate, grow, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayPackedBuilder{&Builder[ArrayUint64Key, uint64]{s: s.Map}}, nil
+}
+
+// Add adds key with value. Panics if value does not fit in the width.
+func (b *ArrayPackedBuilder) Add(key ArrayUint64Key, value uint64) {
+	if err := b.AddE(key, value); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (b *ArrayPackedBuilder) AddE(key ArrayUint64Key, value uint64) error {
+	checkPacked(b.s, value)
+	return b.Builder.AddE(key, value)
+}
+
+// AddSlice adds keys with corresponding values in bulk, see Add
+func (b *ArrayPackedBuilder) AddSlice(keys []ArrayUint64Key, values []uint64) {
+	if err := b.AddSliceE(keys, values); err != nil {
+		panic(err)
+	}
+}
+
+// AddSliceE is like AddSlice, but returns an error if backing arrays cannot be grown
+func (b *ArrayPackedBuilder) AddSliceE(keys []ArrayUint64Key, values []uint64) error {
+	for _, v := range values {
+		checkPacked(b.s, v)
+	}
+	return b.Builder.AddSliceE(keys, values)
+}
+
+func (b *ArrayPackedBuilder) Build() *ArrayPacked {
+	return &ArrayPacked{b.Builder.Build()}
+}
+
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *ArrayPackedBuilder) BuildE() (*ArrayPacked, error) {
+	m, err := b.Builder.BuildE()
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayPacked{m}, nil
+}
@@ -1,9 +1,17 @@
 package sparse
 
 import (
+	"fmt"
+	"math"
 	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"syscall"
 	"testing"
 
+	"github.com/andy722/structures/offheap"
+	"github.com/andy722/structures/range"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,6 +61,27 @@ func TestSparseArrayBuilder_Delete(t *testing.T) {
 	assert.Equal(t, "5", s.Get(5))
 }
 
+func TestArrayIntBuilder_DeleteE(t *testing.T) {
+	b := NewArrayIntBuilder(4, DefaultGrow)
+	b.AddSlice([]ArrayUint64Key{1, 2, 3}, []int{10, 20, 30})
+
+	assert.NoError(t, b.DeleteE(2))
+	b.AddSlice([]ArrayUint64Key{4, 5}, []int{40, 50})
+	assert.NoError(t, b.DeleteE(5))
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, 3, s.Size())
+	assert.Equal(t, 40, s.Get(4))
+
+	prev, err := s.DeleteE(4)
+	assert.NoError(t, err)
+	assert.Equal(t, 40, prev)
+	assert.Equal(t, s.NoValue(), s.Get(4))
+	assert.Equal(t, 30, s.Get(3))
+}
+
 func TestSparseArrayBuilder_Add(t *testing.T) {
 	n := 5000
 
@@ -68,6 +97,102 @@ func TestSparseArrayBuilder_Add(t *testing.T) {
 	}
 }
 
+func TestArrayUint16Builder_AddSlice(t *testing.T) {
+	b := NewArrayUint16Builder()
+
+	b.AddSlice([]ArrayUint64Key{5, 1, 3, 2, 4}, []uint16{50, 10, 30, 20, 40})
+	b.Delete(2)
+	b.Delete(4)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, 3, s.Size())
+	assert.Equal(t, uint16(10), s.Get(1))
+	assert.Equal(t, uint16(30), s.Get(3))
+	assert.Equal(t, uint16(50), s.Get(5))
+	assert.Equal(t, ArrayUint16NoValue, s.Get(2))
+}
+
+func TestSparseArrayBuilder_GC(t *testing.T) {
+	n := 10_000
+
+	b := NewArrayInterfaceBuilder1(16, DefaultGrow)
+	for i, v := range pseudoRandomArray(n) {
+		b.Add(ArrayUint64Key(v), &struct{ s string }{fmt.Sprint(i)})
+	}
+
+	s := b.Build()
+	defer s.Close()
+
+	runtime.GC()
+	runtime.GC()
+
+	for i, v := range pseudoRandomArray(n) {
+		assert.Equal(t, fmt.Sprint(i), s.Get(ArrayUint64Key(v)).(*struct{ s string }).s)
+	}
+}
+
+func TestArrayIntBuilder_Empty(t *testing.T) {
+	b := NewArrayIntBuilder(10, DefaultGrow)
+	assert.NotPanics(t, b.Build().Close)
+
+	b = NewArrayIntBuilder(10, DefaultGrow)
+	b.Add(1, 1)
+	b.Add(2, 2)
+	b.Delete(1)
+	b.Delete(2)
+	s := b.Build()
+	assert.Equal(t, 0, s.Size())
+	assert.NotPanics(t, s.Close)
+}
+
+func TestSparseArray_AllocErrors(t *testing.T) {
+	if strconv.IntSize == 32 {
+		t.Skip("huge arrays may fit in 32-bit address space")
+	}
+
+	const hugeSize = math.MaxInt >> 5
+	_, err := NewSparseArrayIntE(hugeSize, DefaultGrow)
+	assert.ErrorIs(t, err, syscall.ENOMEM)
+
+	_, err = NewArrayUint16Builder1E(hugeSize, DefaultGrow)
+	assert.ErrorIs(t, err, syscall.ENOMEM)
+
+	_, err = NewRangeStoreBuilderE(hugeSize)
+	assert.ErrorIs(t, err, syscall.ENOMEM)
+
+	b, err := NewArrayInterfaceBuilder1E(1, DefaultGrow)
+	assert.NoError(t, err)
+	assert.NoError(t, b.AddE(1, "1"))
+	assert.NoError(t, b.AddE(2, "2"))
+
+	s, err := b.BuildE()
+	assert.NoError(t, err)
+	assert.Equal(t, "2", s.Get(2))
+	s.Close()
+}
+
+func TestArrayIntBuilder_Freeze(t *testing.T) {
+	b := NewArrayIntBuilder(4, DefaultGrow)
+	b.Add(2, 20)
+	b.Add(1, 10)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.NoError(t, s.Freeze())
+	assert.Equal(t, 10, s.Get(1))
+
+	assert.ErrorIs(t, s.AddE(3, 30), offheap.ErrFrozen)
+	assert.ErrorIs(t, b.AddE(3, 30), offheap.ErrFrozen)
+	assert.PanicsWithValue(t, offheap.ErrFrozen, func() { s.Add(1, 11) })
+	assert.PanicsWithValue(t, offheap.ErrFrozen, func() { s.Delete(5) })
+
+	assert.Equal(t, 2, s.Size())
+	assert.Equal(t, 20, s.Get(2))
+}
+
 func BenchmarkSparseArrayBuilder_Add(b *testing.B) {
 	s := NewArrayInterfaceBuilder()
 	items := pseudoRandomArray(b.N)
@@ -128,3 +253,605 @@ func pseudoRandomArray(size int) (rc []int) {
 	rand.Shuffle(len(rc), func(i, j int) { rc[i], rc[j] = rc[j], rc[i] })
 	return rc
 }
+
+func TestRangeStoreBuilder_Options(t *testing.T) {
+	opts := offheap.Options{Access: offheap.AccessRandom, Populate: true}
+
+	b, err := NewRangeStoreBuilderWithOptions(2, opts)
+	assert.NoError(t, err)
+
+	b.Add(10, 19, 1, 2)
+	b.Add(0, 9, 3, 4)
+	b.Add(20, 29, 5, 6)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, opts.Access, s.m.from.Options().Access)
+	assert.Equal(t, opts.Populate, s.m.from.Options().Populate)
+
+	v1, v2, ok := s.Get(5)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(3), v1)
+	assert.Equal(t, uint16(4), v2)
+}
+
+func TestArrayPackedBuilder(t *testing.T) {
+	b, err := NewArrayPackedBuilder1E(12, 4, DefaultGrow)
+	assert.NoError(t, err)
+
+	b.AddSlice([]ArrayUint64Key{30, 10, 20}, []uint64{3, 1, 2})
+	b.Add(40, 4094)
+	b.Delete(20)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, uint64(4095), s.NoValue())
+	assert.Equal(t, 3, s.Size())
+	assert.Equal(t, uint64(1), s.Get(10))
+	assert.Equal(t, s.NoValue(), s.Get(20))
+	assert.Equal(t, uint64(4094), s.Get(40))
+	assert.Panics(t, func() { b.Add(50, 4096) })
+	assert.Equal(t, 3, s.Size())
+
+	s.Add(50, 4095)
+	v, ok := s.Lookup(50)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4095), v)
+}
+
+func TestPackedRangeStoreBuilder(t *testing.T) {
+	b, err := NewPackedRangeStoreBuilderE(20, 2)
+	assert.NoError(t, err)
+
+	b.Add(10, 19, 1<<20-1, 2)
+	b.Add(0, 9, 3, 4)
+	b.Add(20, 29, 5, 6)
+	assert.Panics(t, func() { b.Add(30, 39, 1<<20, 0) })
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, 3, s.Size())
+
+	v1, v2, ok := s.Get(15)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1<<20-1), v1)
+	assert.Equal(t, uint64(2), v2)
+
+	_, _, ok = s.Get(30)
+	assert.False(t, ok)
+}
+
+func TestPackedRangeStore_Widths(t *testing.T) {
+	for _, width := range []uint{1, 7, 20, 32, 33, 64} {
+		b := NewPackedRangeStoreBuilder(width, 4)
+		max := ^uint64(0) >> (64 - width)
+		b.Add(20, 29, max, 0)
+		b.Add(0, 9, 0, max)
+		b.Add(10, 19, max/3, max/2)
+		s := b.Build()
+
+		// Both values share the bytes after the range end
+		assert.Equal(t, 8+int(2*width+7)/8, s.m.records.Stride(), "width %d", width)
+
+		for _, want := range [][3]uint64{{25, max, 0}, {5, 0, max}, {15, max / 3, max / 2}} {
+			v1, v2, ok := s.Get(want[0])
+			assert.True(t, ok)
+			assert.Equal(t, []uint64{want[1], want[2]}, []uint64{v1, v2}, "width %d", width)
+		}
+		s.Close()
+	}
+
+	_, err := NewPackedRangeStoreE(65, 1, DefaultGrow)
+	assert.Error(t, err)
+}
+
+func TestPackedRangeStore_Empty(t *testing.T) {
+	s := NewPackedRangeStore(20, 2, DefaultGrow)
+	defer s.Close()
+
+	_, _, ok := s.Get(0)
+	assert.False(t, ok)
+	_, _, ok = s.Get(100)
+	assert.False(t, ok)
+}
+
+func TestArrayBytesBuilder(t *testing.T) {
+	b := NewArrayBytesBuilder1(2, DefaultGrow)
+
+	b.AddString(30, "thirty")
+	b.AddString(10, "ten")
+	b.Add(20, []byte{})
+	b.AddString(40, "forty")
+	b.Delete(40)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, 3, s.Size())
+
+	v, ok := s.GetString(10)
+	assert.True(t, ok)
+	assert.Equal(t, "ten", v)
+
+	empty, ok := s.Get(20)
+	assert.True(t, ok)
+	assert.Empty(t, empty)
+
+	_, ok = s.Get(40)
+	assert.False(t, ok)
+
+	s.Add(10, []byte("TEN"))
+	s.Add(15, []byte("fifteen"))
+	v, _ = s.GetString(10)
+	assert.Equal(t, "TEN", v)
+	v, _ = s.GetString(15)
+	assert.Equal(t, "fifteen", v)
+
+	prev, ok := s.Delete(30)
+	assert.True(t, ok)
+	assert.Equal(t, "thirty", string(prev))
+	_, ok = s.Get(30)
+	assert.False(t, ok)
+
+	// Replaced and deleted values are reclaimed by Compact
+	assert.Equal(t, 5, s.arena.Len())
+	assert.NoError(t, s.Compact())
+	assert.Equal(t, 3, s.arena.Len())
+	assert.Equal(t, len("TEN")+len("fifteen"), s.arena.Size())
+	v, _ = s.GetString(10)
+	assert.Equal(t, "TEN", v)
+	v, _ = s.GetString(15)
+	assert.Equal(t, "fifteen", v)
+	_, ok = s.Get(30)
+	assert.False(t, ok)
+}
+
+func TestRangeMapBuilder(t *testing.T) {
+	type location struct {
+		Region  uint32
+		Country uint16
+		Zone    uint8
+	}
+
+	b, err := NewRangeMapBuilderE[location](1)
+	assert.NoError(t, err)
+
+	b.Add(100, 199, location{1, 2, 3})
+	b.Add(0, 99, location{4, 5, 6})
+	b.Add(300, 399, location{1, 2, 3})
+
+	s := b.Build()
+	defer s.Close()
+
+	v, ok := s.Get(150)
+	assert.True(t, ok)
+	assert.Equal(t, location{1, 2, 3}, v)
+
+	v, ok = s.Get(0)
+	assert.True(t, ok)
+	assert.Equal(t, location{4, 5, 6}, v)
+
+	_, ok = s.Get(250)
+	assert.False(t, ok)
+	_, ok = s.Get(400)
+	assert.False(t, ok)
+
+	var values []location
+	s.Values(func(v location) { values = append(values, v) })
+	assert.Equal(t, []location{{4, 5, 6}, {1, 2, 3}}, values)
+
+	// End and payload are stored in a single unpadded record
+	assert.Equal(t, 8+8, s.records.Stride())
+	rs := NewSparseRangeStore(1, DefaultGrow)
+	assert.Equal(t, 8+4, rs.m.records.Stride())
+	rs.Close()
+
+	_, err = NewRangeMapE[*location](1, DefaultGrow)
+	assert.Error(t, err)
+}
+
+func TestArrayInterfaceBuilder_SortsOnlyUnsorted(t *testing.T) {
+	b := NewArrayInterfaceBuilder1(16, DefaultGrow)
+
+	for i := 0; i < 10; i++ {
+		b.Add(ArrayUint64Key(i), i)
+	}
+	assert.False(t, b.shouldSort)
+
+	b.Delete(3)
+	b.Delete(4)
+	b.Delete(8)
+	s := b.Build()
+	assert.False(t, b.shouldSort)
+
+	var keys []ArrayUint64Key
+	for i := 0; i < s.Size(); i++ {
+		keys = append(keys, s.keys.Get(i))
+	}
+	assert.Equal(t, []ArrayUint64Key{0, 1, 2, 5, 6, 7, 9}, keys)
+	assert.Nil(t, s.Get(4))
+	assert.Equal(t, 9, s.Get(9))
+
+	b.Add(5, "replaced")
+	assert.True(t, b.shouldSort)
+	s.Close()
+}
+
+func TestBuilder_Generic(t *testing.T) {
+	type point struct {
+		X, Y int32
+	}
+
+	b := NewBuilder[uint32, point](4, DefaultGrow)
+	b.Add(30, point{3, 3})
+	b.Add(10, point{1, 1})
+	b.AddSlice([]uint32{20, 40}, []point{{2, 2}, {4, 4}})
+	b.Delete(40)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, 3, s.Size())
+	assert.Equal(t, point{1, 1}, s.Get(10))
+	assert.Equal(t, point{2, 2}, s.Get(20))
+	assert.Equal(t, point{}, s.Get(40))
+
+	s.Add(15, point{5, 5})
+	assert.Equal(t, point{5, 5}, s.Delete(15))
+	assert.Equal(t, s.NoValue(), s.Get(15))
+
+	_, err := NewMapE[uint64, *point](1, DefaultGrow)
+	assert.Error(t, err)
+}
+
+func TestArrayInt_StoresNoValue(t *testing.T) {
+	b := NewArrayIntBuilder(4, DefaultGrow)
+	b.Add(20, NoValue)
+	b.Add(10, 1)
+	b.Add(30, NoValue)
+	b.Delete(30)
+
+	s := b.Build()
+	defer s.Close()
+
+	assert.Equal(t, 2, s.Size())
+
+	v, ok := s.Lookup(20)
+	assert.True(t, ok)
+	assert.Equal(t, NoValue, v)
+
+	_, ok = s.Lookup(30)
+	assert.False(t, ok)
+
+	var values []int
+	s.Values(func(v int) { values = append(values, v) })
+	assert.Equal(t, []int{1, NoValue}, values)
+
+	assert.Equal(t, NoValue, s.Delete(20))
+	_, ok = s.Lookup(20)
+	assert.False(t, ok)
+
+	values = nil
+	s.Values(func(v int) { values = append(values, v) })
+	assert.Equal(t, []int{1}, values)
+
+	s.Add(20, 2)
+	assert.Equal(t, 2, s.Get(20))
+}
+
+func TestArrayUint16_StoresNoValue(t *testing.T) {
+	s := NewSparseArrayUint16(4, DefaultGrow)
+	defer s.Close()
+
+	s.Add(1, ArrayUint16NoValue)
+	s.Add(2, 7)
+	s.Delete(2)
+	s.Add(0, 5)
+
+	v, ok := s.Lookup(1)
+	assert.True(t, ok)
+	assert.Equal(t, ArrayUint16NoValue, v)
+
+	_, ok = s.Lookup(2)
+	assert.False(t, ok)
+	assert.Equal(t, ArrayUint16NoValue, s.Get(2))
+
+	v, ok = s.Lookup(0)
+	assert.True(t, ok)
+	assert.Equal(t, uint16(5), v)
+}
+
+func TestArrayInt_Ascend(t *testing.T) {
+	b := NewArrayIntBuilder(8, DefaultGrow)
+	for _, k := range []ArrayUint64Key{50, 10, 40, 20, 30} {
+		b.Add(k, int(k))
+	}
+	s := b.Build()
+	defer s.Close()
+	s.Delete(30)
+
+	var keys []ArrayUint64Key
+	collect := func(key ArrayUint64Key, val int) bool {
+		assert.Equal(t, int(key), val)
+		keys = append(keys, key)
+		return true
+	}
+
+	s.Ascend(collect)
+	assert.Equal(t, []ArrayUint64Key{10, 20, 40, 50}, keys)
+
+	keys = nil
+	s.Descend(collect)
+	assert.Equal(t, []ArrayUint64Key{50, 40, 20, 10}, keys)
+
+	keys = nil
+	s.AscendRange(15, 50, collect)
+	assert.Equal(t, []ArrayUint64Key{20, 40, 50}, keys)
+
+	keys = nil
+	s.AscendRange(40, 40, collect)
+	assert.Equal(t, []ArrayUint64Key{40}, keys)
+
+	keys = nil
+	s.AscendRange(50, 15, collect)
+	assert.Empty(t, keys)
+
+	// Bounds are inclusive, same as in CountRange
+	assert.Equal(t, 3, s.CountRange(15, 50))
+
+	keys = nil
+	s.Ascend(func(key ArrayUint64Key, val int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	assert.Equal(t, []ArrayUint64Key{10, 20}, keys)
+}
+
+func TestArrayBytes_Ascend(t *testing.T) {
+	b := NewArrayBytesBuilder1(4, DefaultGrow)
+	b.AddString(2, "two")
+	b.AddString(1, "one")
+	b.AddString(3, "three")
+	s := b.Build()
+	defer s.Close()
+
+	var values []string
+	s.AscendRange(2, 10, func(key ArrayUint64Key, val []byte) bool {
+		values = append(values, string(val))
+		return true
+	})
+	assert.Equal(t, []string{"two", "three"}, values)
+
+	values = nil
+	s.Descend(func(key ArrayUint64Key, val []byte) bool {
+		values = append(values, string(val))
+		return true
+	})
+	assert.Equal(t, []string{"three", "two", "one"}, values)
+}
+
+func TestRangeStore_AscendRange(t *testing.T) {
+	b := NewRangeStoreBuilder(4)
+	b.Add(20, 29, 2, 20)
+	b.Add(0, 9, 0, 0)
+	b.Add(10, 19, 1, 10)
+	b.Add(40, 49, 4, 40)
+	s := b.Build()
+	defer s.Close()
+
+	var starts []_range.RangePoint
+	collect := func(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) bool {
+		assert.Equal(t, fromIncl+9, toIncl)
+		assert.Equal(t, uint16(fromIncl/10), v1)
+		starts = append(starts, fromIncl)
+		return true
+	}
+
+	s.AscendRange(15, 40, collect)
+	assert.Equal(t, []_range.RangePoint{10, 20, 40}, starts)
+
+	starts = nil
+	s.AscendRange(30, 41, collect)
+	assert.Equal(t, []_range.RangePoint{40}, starts)
+
+	starts = nil
+	s.AscendRange(30, 39, collect)
+	assert.Empty(t, starts)
+
+	starts = nil
+	s.AscendRange(29, 29, collect)
+	assert.Equal(t, []_range.RangePoint{20}, starts)
+
+	starts = nil
+	s.Descend(collect)
+	assert.Equal(t, []_range.RangePoint{40, 20, 10, 0}, starts)
+}
+
+func TestPackedRangeStore_Ascend(t *testing.T) {
+	b := NewPackedRangeStoreBuilder(8, 4)
+	b.Add(10, 19, 1, 2)
+	b.Add(0, 9, 3, 4)
+	s := b.Build()
+	defer s.Close()
+
+	var values []uint64
+	s.Ascend(func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool {
+		values = append(values, v1, v2)
+		return true
+	})
+	assert.Equal(t, []uint64{3, 4, 1, 2}, values)
+
+	values = nil
+	s.AscendRange(19, 20, func(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) bool {
+		values = append(values, v1, v2)
+		return true
+	})
+	assert.Equal(t, []uint64{1, 2}, values)
+}
+
+func TestArrayUint16_Nearest(t *testing.T) {
+	s := NewSparseArrayUint16(8, DefaultGrow)
+	defer s.Close()
+	for _, k := range []ArrayUint64Key{10, 20, 30, 40} {
+		s.Add(k, uint16(k))
+	}
+	s.Delete(30)
+
+	type entry struct {
+		key ArrayUint64Key
+		val uint16
+		ok  bool
+	}
+	at := func(key ArrayUint64Key, val uint16, ok bool) entry { return entry{key, val, ok} }
+
+	assert.Equal(t, entry{20, 20, true}, at(s.Floor(20)))
+	assert.Equal(t, entry{20, 20, true}, at(s.Floor(35)))
+	assert.False(t, at(s.Floor(5)).ok)
+
+	assert.Equal(t, entry{20, 20, true}, at(s.Ceiling(20)))
+	assert.Equal(t, entry{40, 40, true}, at(s.Ceiling(21)))
+	assert.False(t, at(s.Ceiling(41)).ok)
+
+	assert.Equal(t, entry{10, 10, true}, at(s.Lower(20)))
+	assert.Equal(t, entry{20, 20, true}, at(s.Lower(40)))
+	assert.False(t, at(s.Lower(10)).ok)
+
+	assert.Equal(t, entry{40, 40, true}, at(s.Higher(20)))
+	assert.Equal(t, entry{10, 10, true}, at(s.Higher(0)))
+	assert.False(t, at(s.Higher(40)).ok)
+}
+
+func TestArrayBytes_Nearest(t *testing.T) {
+	s := NewSparseArrayBytes(4, DefaultGrow)
+	defer s.Close()
+	s.Add(10, []byte("ten"))
+	s.Add(20, []byte("twenty"))
+
+	key, val, ok := s.Floor(15)
+	assert.True(t, ok)
+	assert.Equal(t, ArrayUint64Key(10), key)
+	assert.Equal(t, "ten", string(val))
+
+	key, val, ok = s.Higher(10)
+	assert.True(t, ok)
+	assert.Equal(t, ArrayUint64Key(20), key)
+	assert.Equal(t, "twenty", string(val))
+
+	_, val, ok = s.Ceiling(21)
+	assert.False(t, ok)
+	assert.Nil(t, val)
+}
+
+func TestArrayInt_Rank(t *testing.T) {
+	s := NewSparseArrayInt(16, DefaultGrow)
+	defer s.Close()
+
+	for k := ArrayUint64Key(0); k < 100; k += 10 {
+		s.Add(k, int(k))
+	}
+
+	assert.Equal(t, 0, s.Rank(0))
+	assert.Equal(t, 3, s.Rank(25))
+	assert.Equal(t, 10, s.Rank(1000))
+	assert.Equal(t, 3, s.CountRange(10, 30))
+	assert.Equal(t, 0, s.CountRange(30, 10))
+
+	key, val, ok := s.Select(3)
+	assert.True(t, ok)
+	assert.Equal(t, ArrayUint64Key(30), key)
+	assert.Equal(t, 30, val)
+
+	s.Delete(0)
+	s.Delete(20)
+	s.Delete(90)
+
+	assert.Equal(t, 1, s.Rank(25))
+	assert.Equal(t, 7, s.Rank(1000))
+	assert.Equal(t, 2, s.CountRange(10, 30))
+	assert.Equal(t, 7, s.CountRange(0, 90))
+
+	var keys []ArrayUint64Key
+	for k := 0; ; k++ {
+		key, _, ok := s.Select(k)
+		if !ok {
+			break
+		}
+		assert.Equal(t, k, s.Rank(key))
+		keys = append(keys, key)
+	}
+	assert.Equal(t, []ArrayUint64Key{10, 30, 40, 50, 60, 70, 80}, keys)
+
+	_, _, ok = s.Select(-1)
+	assert.False(t, ok)
+}
+
+func TestArrayInt_Rank_Blocks(t *testing.T) {
+	const n = 5 * rankBlock
+	s := NewSparseArrayInt(n, DefaultGrow)
+	defer s.Close()
+
+	for k := 0; k < n; k++ {
+		s.Add(ArrayUint64Key(k), k)
+	}
+
+	check := func() {
+		var live []ArrayUint64Key
+		for k := ArrayUint64Key(0); k <= n; k++ {
+			if _, ok := s.Lookup(k); ok {
+				live = append(live, k)
+			}
+		}
+
+		for i, k := range live {
+			assert.Equal(t, i, s.Rank(k))
+			key, _, ok := s.Select(i)
+			assert.True(t, ok)
+			assert.Equal(t, k, key)
+		}
+		_, _, ok := s.Select(len(live))
+		assert.False(t, ok)
+		assert.Equal(t, len(live), s.CountRange(0, n))
+	}
+
+	for k := 0; k < n; k += 3 {
+		s.Delete(ArrayUint64Key(k))
+	}
+	check()
+
+	// Changes after a query are reflected in the index
+	for k := rankBlock; k < 2*rankBlock; k++ {
+		s.Delete(ArrayUint64Key(k))
+	}
+	s.Add(0, 0)
+	s.Add(n, n)
+	check()
+
+	assert.NoError(t, s.Freeze())
+	check()
+}
+
+func TestArrayUint32Uint16Sorter(t *testing.T) {
+	s := NewArrayUint32Uint16(radixThreshold, DefaultGrow)
+	defer s.Close()
+	for i := radixThreshold; i > 0; i-- {
+		s.keys.Append(ArrayUint32Key(i))
+		s.values.Append(offheap.ArrayUint16Value(i % 1000))
+	}
+
+	sorter := ArrayUint32Uint16Sorter(func() *ArrayUint32Uint16 { return s })
+	assert.True(t, sorter.Less(1, 0))
+	sorter.Swap(0, 1)
+	assert.Equal(t, ArrayUint32Key(radixThreshold-1), s.keys.Get(0))
+
+	sorter.Sort()
+	for i := 0; i < s.Size(); i++ {
+		assert.Equal(t, ArrayUint32Key(i+1), s.keys.Get(i))
+		assert.Equal(t, offheap.ArrayUint16Value((i+1)%1000), s.Get(ArrayUint32Key(i+1)))
+	}
+
+	sort.Sort(sort.Reverse(sorter))
+	assert.Equal(t, ArrayUint32Key(radixThreshold), s.keys.Get(0))
+}
@@ -1,95 +1,39 @@
 package sparse
 
-import (
-	"github.com/andy722/structures/offheap"
-	"sort"
-)
+import "github.com/andy722/structures/offheap"
 
+// ArrayUint16NoValue is returned by Get for missing keys. It can be stored as well, see Map.Lookup.
 const ArrayUint16NoValue uint16 = 65535
 
-// ArrayUint16 provides an off-heap map with numeric keys, internally represented as sparse array
+// ArrayUint16 provides an off-heap map with numeric keys, internally represented as sparse array, see Map
 type ArrayUint16 struct {
-	arrayUint64
-
-	values *offheap.ArrayUint16
+	*Map[ArrayUint64Key, offheap.ArrayUint16Value]
 }
 
 func NewSparseArrayUint16(preallocate int, grow float64) *ArrayUint16 {
-	return &ArrayUint16{
-		arrayUint64{
-			preallocate,
-			grow,
-			offheap.NewArrayUint64(preallocate),
-		},
-		offheap.NewArrayUint16(preallocate),
-	}
-}
-
-func (s *ArrayUint16) Close() {
-	s.arrayUint64.Close()
-	s.values.Dealloc()
-}
-
-func (s *ArrayUint16) Add(key ArrayUint64Key, val offheap.ArrayUint16Value) {
-	i := s.idx(key)
-	if i < s.keys.Len() && s.keys.Get(i) == key {
-		s.values.Set(i, val)
-		return
-	}
-
-	s.growBackingArraysIfNeeded()
-
-	s.keys.Insert(i, key)
-	s.values.Insert(i, val)
-}
-
-func (s *ArrayUint16) Get(key ArrayUint64Key) offheap.ArrayUint16Value {
-	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key {
-		return s.values.Get(i)
-	}
-	return ArrayUint16NoValue
-}
-
-func (s *ArrayUint16) Delete(key ArrayUint64Key) (prev offheap.ArrayUint16Value) {
-	if i := s.idx(key); i < s.Size() && s.keys.Get(i) == key {
-		prev = s.values.Get(i)
-		s.values.Set(i, ArrayUint16NoValue)
-	}
-	return
-}
-
-func (s *ArrayUint16) growBackingArraysIfNeeded() {
-	size := s.Size()
-	if size < s.cap() {
-		return
+	s, err := NewSparseArrayUint16E(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
-
-	newSize := int(s.grow * float64(size))
-
-	s.keys = s.keys.Grow(newSize)
-	s.values = s.values.Grow(newSize)
+	return s
 }
 
-func (s *ArrayUint16) cleanup() {
-	for i := s.Size() - 1; i >= 0; i-- {
-		if s.values.Get(i) == ArrayUint16NoValue {
-			s.keys.Remove(i)
-			s.values.Remove(i)
-		}
-	}
+// NewSparseArrayUint16E is like NewSparseArrayUint16, but returns an error if off-heap memory cannot be allocated
+func NewSparseArrayUint16E(preallocate int, grow float64) (*ArrayUint16, error) {
+	return NewSparseArrayUint16WithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (s *ArrayUint16) shrink() {
-	if size := s.Size(); size < s.cap() {
-		s.keys = s.keys.TrimToSize()
-		s.values = s.values.TrimToSize()
+// NewSparseArrayUint16WithOptions is like NewSparseArrayUint16E, with backing arrays mapped according to opts
+func NewSparseArrayUint16WithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayUint16, error) {
+	m, err := newMap[ArrayUint64Key](preallocate, grow, opts, newArrayColumn[offheap.ArrayUint16Value], ArrayUint16NoValue)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayUint16{m}, nil
 }
 
 type ArrayUint16Builder struct {
-	s             *ArrayUint16
-	shouldSort    bool // Marks as containing non-sorted data, need to sort prior to lookups
-	shouldCleanup bool // Marks as containing gaps, i.e. deleted entries
+	*Builder[ArrayUint64Key, offheap.ArrayUint16Value]
 }
 
 //goland:noinspection GoUnusedExportedFunction
@@ -97,63 +41,41 @@ func NewArrayUint16Builder() *ArrayUint16Builder {
 	return NewArrayUint16Builder1(DefaultPreallocate, DefaultGrow)
 }
 
+func NewArrayUint16BuilderE() (*ArrayUint16Builder, error) {
+	return NewArrayUint16Builder1E(DefaultPreallocate, DefaultGrow)
+}
+
 func NewArrayUint16Builder1(preallocate int, grow float64) *ArrayUint16Builder {
-	return &ArrayUint16Builder{
-		s: NewSparseArrayUint16(preallocate, grow),
+	b, err := NewArrayUint16Builder1E(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
+	return b
 }
 
-func (b *ArrayUint16Builder) Add(key ArrayUint64Key, value offheap.ArrayUint16Value) {
-	b.shouldSort = true
-
-	b.s.growBackingArraysIfNeeded()
-
-	b.s.keys.Append(key)
-	b.s.values.Append(value)
+func NewArrayUint16Builder1E(preallocate int, grow float64) (*ArrayUint16Builder, error) {
+	return NewArrayUint16BuilderWithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (b *ArrayUint16Builder) Delete(key ArrayUint64Key) {
-	if b.shouldSort {
-		b.sort()
-	}
-
-	if b.s.Delete(key) != ArrayUint16NoValue {
-		b.shouldCleanup = true
+// NewArrayUint16BuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewArrayUint16BuilderWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayUint16Builder, error) {
+	s, err := NewSparseArrayUint16WithOptions(preallocate, grow, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayUint16Builder{&Builder[ArrayUint64Key, offheap.ArrayUint16Value]{s: s.Map}}, nil
 }
 
 func (b *ArrayUint16Builder) Build() *ArrayUint16 {
-	if b.shouldCleanup {
-		b.s.cleanup()
-		b.shouldCleanup = false
-	}
-
-	if b.shouldSort {
-		b.sort()
-	}
-
-	b.s.shrink()
-
-	return b.s
-}
-
-func (b *ArrayUint16Builder) sort() {
-	sort.Sort(sparseArrayUint16Sorter(func() *ArrayUint16 { return b.s }))
-	b.shouldSort = false
+	return &ArrayUint16{b.Builder.Build()}
 }
 
-type sparseArrayUint16Sorter func() *ArrayUint16
-
-func (s sparseArrayUint16Sorter) Len() int {
-	return s().Size()
-}
-
-func (s sparseArrayUint16Sorter) Less(i, j int) bool {
-	keys := s().keys
-	return keys.Get(i) < keys.Get(j)
-}
-
-func (s sparseArrayUint16Sorter) Swap(i, j int) {
-	s().keys.Swap(i, j)
-	s().values.Swap(i, j)
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *ArrayUint16Builder) BuildE() (*ArrayUint16, error) {
+	m, err := b.Builder.BuildE()
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayUint16{m}, nil
 }
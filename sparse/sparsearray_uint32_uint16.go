@@ -1,90 +1,36 @@
 package sparse
 
-import (
-	"github.com/andy722/structures/offheap"
-	"sort"
-)
+import "github.com/andy722/structures/offheap"
 
-// ArrayUint32Uint16 provides an off-heap map with numeric keys, internally represented as sparse array
+// ArrayUint32Uint16 provides an off-heap map with numeric keys, internally represented as sparse array, see Map
 type ArrayUint32Uint16 struct {
-	arrayUint32
-
-	values *offheap.ArrayUint16
-	size   int
+	*Map[ArrayUint32Key, offheap.ArrayUint16Value]
 }
 
 func NewArrayUint32Uint16(preallocate int, grow float64) *ArrayUint32Uint16 {
-	return &ArrayUint32Uint16{
-		arrayUint32{
-			preallocate,
-			grow,
-			offheap.NewArrayUint32(preallocate),
-		},
-		offheap.NewArrayUint16(preallocate),
-		0,
-	}
-}
-
-func (s *ArrayUint32Uint16) Close() {
-	s.arrayUint32.Close()
-	s.values.Dealloc()
-}
-
-func (s *ArrayUint32Uint16) Get(key ArrayUint32Key) offheap.ArrayUint16Value {
-	if i := s.idx(key); i < s.size && s.keys.Get(i) == key {
-		return s.values.Get(i)
-	}
-	return ArrayUint16NoValue
-}
-
-func (s *ArrayUint32Uint16) Values(callback func(uint16)) {
-	s.values.Values(callback)
-}
-
-func (s *ArrayUint32Uint16) idx(key ArrayUint32Key) int {
-	return sort.Search(s.size, func(i int) bool { return s.keys.Get(i) >= key })
-}
-
-func (s *ArrayUint32Uint16) Delete(key ArrayUint32Key) (prev offheap.ArrayUint16Value) {
-	if i := s.idx(key); i < s.size && s.keys.Get(i) == key {
-		prev = s.values.Get(i)
-		s.values.Set(i, ArrayUint16NoValue)
+	s, err := NewArrayUint32Uint16E(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
-	return
-}
-
-func (s *ArrayUint32Uint16) growBackingArraysIfNeeded() {
-	size := s.Size()
-	if size < s.cap() {
-		return
-	}
-
-	newSize := int(s.grow * float64(size))
-
-	s.keys = s.keys.Grow(newSize)
-	s.values = s.values.Grow(newSize)
+	return s
 }
 
-func (s *ArrayUint32Uint16) cleanup() {
-	for i := s.Size() - 1; i >= 0; i-- {
-		if s.values.Get(i) == ArrayUint16NoValue {
-			s.keys.Remove(i)
-			s.values.Remove(i)
-		}
-	}
+// NewArrayUint32Uint16E is like NewArrayUint32Uint16, but returns an error if off-heap memory cannot be allocated
+func NewArrayUint32Uint16E(preallocate int, grow float64) (*ArrayUint32Uint16, error) {
+	return NewArrayUint32Uint16WithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (s *ArrayUint32Uint16) shrink() {
-	if size := s.Size(); size < s.cap() {
-		s.keys = s.keys.TrimToSize()
-		s.values = s.values.TrimToSize()
+// NewArrayUint32Uint16WithOptions is like NewArrayUint32Uint16E, with backing arrays mapped according to opts
+func NewArrayUint32Uint16WithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayUint32Uint16, error) {
+	m, err := newMap[ArrayUint32Key](preallocate, grow, opts, newArrayColumn[offheap.ArrayUint16Value], ArrayUint16NoValue)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayUint32Uint16{m}, nil
 }
 
 type ArrayUint32Uint16Builder struct {
-	s             *ArrayUint32Uint16
-	shouldSort    bool // Marks as containing non-sorted data, need to sort prior to lookups
-	shouldCleanup bool // Marks as containing gaps, i.e. deleted entries
+	*Builder[ArrayUint32Key, offheap.ArrayUint16Value]
 }
 
 //goland:noinspection GoUnusedExportedFunction
@@ -92,53 +38,49 @@ func NewArrayUint32Uint16Builder() *ArrayUint32Uint16Builder {
 	return NewArrayUint32Uint16Builder1(DefaultPreallocate, DefaultGrow)
 }
 
+func NewArrayUint32Uint16BuilderE() (*ArrayUint32Uint16Builder, error) {
+	return NewArrayUint32Uint16Builder1E(DefaultPreallocate, DefaultGrow)
+}
+
 func NewArrayUint32Uint16Builder1(preallocate int, grow float64) *ArrayUint32Uint16Builder {
-	return &ArrayUint32Uint16Builder{
-		s: NewArrayUint32Uint16(preallocate, grow),
+	b, err := NewArrayUint32Uint16Builder1E(preallocate, grow)
+	if err != nil {
+		panic(err)
 	}
+	return b
 }
 
-func (b *ArrayUint32Uint16Builder) Add(key ArrayUint32Key, value offheap.ArrayUint16Value) {
-	b.shouldSort = true
-
-	b.s.growBackingArraysIfNeeded()
-
-	b.s.keys.Append(key)
-	b.s.values.Append(value)
+func NewArrayUint32Uint16Builder1E(preallocate int, grow float64) (*ArrayUint32Uint16Builder, error) {
+	return NewArrayUint32Uint16BuilderWithOptions(preallocate, grow, offheap.Options{})
 }
 
-func (b *ArrayUint32Uint16Builder) Delete(key ArrayUint32Key) {
-	if b.shouldSort {
-		b.sort()
-	}
-
-	if b.s.Delete(key) != ArrayUint16NoValue {
-		b.shouldCleanup = true
+// NewArrayUint32Uint16BuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built structure.
+func NewArrayUint32Uint16BuilderWithOptions(preallocate int, grow float64, opts offheap.Options) (*ArrayUint32Uint16Builder, error) {
+	s, err := NewArrayUint32Uint16WithOptions(preallocate, grow, opts)
+	if err != nil {
+		return nil, err
 	}
+	return &ArrayUint32Uint16Builder{&Builder[ArrayUint32Key, offheap.ArrayUint16Value]{s: s.Map}}, nil
 }
 
 func (b *ArrayUint32Uint16Builder) Build() *ArrayUint32Uint16 {
-	if b.shouldCleanup {
-		b.s.cleanup()
-		b.shouldCleanup = false
-	}
-
-	if b.shouldSort {
-		b.sort()
-	}
-
-	b.s.shrink()
-
-	b.s.size = b.s.Size()
-
-	return b.s
+	return &ArrayUint32Uint16{b.Builder.Build()}
 }
 
-func (b *ArrayUint32Uint16Builder) sort() {
-	sort.Sort(ArrayUint32Uint16Sorter(func() *ArrayUint32Uint16 { return b.s }))
-	b.shouldSort = false
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *ArrayUint32Uint16Builder) BuildE() (*ArrayUint32Uint16, error) {
+	m, err := b.Builder.BuildE()
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayUint32Uint16{m}, nil
 }
 
+// ArrayUint32Uint16Sorter implements sort.Interface over entries of a map.
+//
+// Deprecated: builders sort entries on Build. Use Sort rather than sort.Sort, as it sorts large maps
+// with radix sort.
 type ArrayUint32Uint16Sorter func() *ArrayUint32Uint16
 
 func (s ArrayUint32Uint16Sorter) Len() int {
@@ -146,11 +88,19 @@ func (s ArrayUint32Uint16Sorter) Len() int {
 }
 
 func (s ArrayUint32Uint16Sorter) Less(i, j int) bool {
-	keys := s().keys
-	return keys.Get(i) < keys.Get(j)
+	return s.sorter().Less(i, j)
 }
 
 func (s ArrayUint32Uint16Sorter) Swap(i, j int) {
-	s().keys.Swap(i, j)
-	s().values.Swap(i, j)
+	s.sorter().Swap(i, j)
+}
+
+// Sort orders entries by keys, reordering values with them
+func (s ArrayUint32Uint16Sorter) Sort() {
+	s().sort()
+}
+
+func (s ArrayUint32Uint16Sorter) sorter() mapSorter[ArrayUint32Key, offheap.ArrayUint16Value] {
+	m := s().Map
+	return func() *Map[ArrayUint32Key, offheap.ArrayUint16Value] { return m }
 }
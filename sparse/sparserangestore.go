@@ -3,155 +3,110 @@ package sparse
 import (
 	"github.com/andy722/structures/offheap"
 	"github.com/andy722/structures/range"
-	"sort"
 )
 
 // RangeStore maps inclusive range [fromIncl, toIncl] to value
 type RangeStore struct {
-	grow float64
+	m RangeMap[rangeStoreValue]
+}
 
-	from, end *offheap.ArrayUint64
-	v1, v2    *offheap.ArrayUint16
+type rangeStoreValue struct {
+	v1, v2 uint16
 }
 
 func NewSparseRangeStore(initialSize int, grow float64) RangeStore {
-	return RangeStore{
-		grow: grow,
-		from: offheap.NewArrayUint64(initialSize),
-		end:  offheap.NewArrayUint64(initialSize),
-		v1:   offheap.NewArrayUint16(initialSize),
-		v2:   offheap.NewArrayUint16(initialSize),
+	s, err := NewSparseRangeStoreE(initialSize, grow)
+	if err != nil {
+		panic(err)
 	}
+	return s
 }
 
-func (s *RangeStore) Get(key ArrayUint64Key) (v1 uint16, v2 uint16, exists bool) {
-	idx := sort.Search(s.Size(), func(i int) bool { return s.from.Get(i) >= key })
-	if idx >= s.Size() {
-		// Check if the last element matches
-		return s.checkMatch(key, idx-1)
-	}
-
-	if v1, v2, exists = s.checkMatch(key, idx); exists {
-		return
-	}
+// NewSparseRangeStoreE is like NewSparseRangeStore, but returns an error if off-heap memory cannot be allocated
+func NewSparseRangeStoreE(initialSize int, grow float64) (RangeStore, error) {
+	return NewSparseRangeStoreWithOptions(initialSize, grow, offheap.Options{})
+}
 
-	if idx > 0 {
-		return s.checkMatch(key, idx-1)
-	}
+// NewSparseRangeStoreWithOptions is like NewSparseRangeStoreE, with backing arrays mapped according to opts
+func NewSparseRangeStoreWithOptions(initialSize int, grow float64, opts offheap.Options) (RangeStore, error) {
+	m, err := NewRangeMapWithOptions[rangeStoreValue](initialSize, grow, opts)
+	return RangeStore{m: m}, err
+}
 
-	return
+func (s *RangeStore) Get(key ArrayUint64Key) (v1 uint16, v2 uint16, exists bool) {
+	v, exists := s.m.Get(key)
+	return v.v1, v.v2, exists
 }
 
 func (s *RangeStore) ValuesV1(callback func(uint16)) {
-	s.v1.Values(callback)
+	rangeValues(&s.m, callback, func(v rangeStoreValue) uint16 { return v.v1 })
 }
 
 func (s *RangeStore) ValuesV2(callback func(uint16)) {
-	s.v2.Values(callback)
-}
-
-func (s *RangeStore) checkMatch(key ArrayUint64Key, idx int) (v1, v2 uint16, exists bool) {
-	if rangeStart := s.from.Get(idx); rangeStart > key {
-		return
-	}
-
-	if rangeEnd := s.end.Get(idx); rangeEnd < key {
-		return
-	}
-
-	return s.v1.Get(idx), s.v2.Get(idx), true
+	rangeValues(&s.m, callback, func(v rangeStoreValue) uint16 { return v.v2 })
 }
 
 func (s *RangeStore) Size() int {
-	return s.from.Len()
+	return s.m.Size()
 }
 
-func (s *RangeStore) cap() int {
-	return s.from.Cap()
+// Freeze makes the store read-only, see offheap.Array.Freeze
+func (s *RangeStore) Freeze() error {
+	return s.m.Freeze()
 }
 
-func (s *RangeStore) shrink() {
-	if size := s.Size(); size < s.cap() {
-		s.from = s.from.TrimToSize()
-		s.end = s.end.TrimToSize()
-		s.v1 = s.v1.TrimToSize()
-		s.v2 = s.v2.TrimToSize()
-	}
-}
-
-func (s *RangeStore) growBackingArraysIfNeeded() {
-	size := s.Size()
-	if size < s.cap() {
-		return
-	}
-
-	newSize := int(s.grow * float64(size))
-
-	s.from = s.from.Grow(newSize)
-	s.end = s.end.Grow(newSize)
-	s.v1 = s.v1.Grow(newSize)
-	s.v2 = s.v2.Grow(newSize)
+// Frozen tells if the store was made read-only with Freeze
+func (s *RangeStore) Frozen() bool {
+	return s.m.Frozen()
 }
 
 func (s *RangeStore) Close() {
-	s.from.Dealloc()
-	s.end.Dealloc()
-	s.v1.Dealloc()
-	s.v2.Dealloc()
+	s.m.Close()
 }
 
 type RangeStoreBuilder struct {
-	s          RangeStore
-	shouldSort bool
+	b RangeMapBuilder[rangeStoreValue]
 }
 
 //goland:noinspection GoUnusedExportedFunction
 func NewRangeStoreBuilder(initialSize int) RangeStoreBuilder {
 	return RangeStoreBuilder{
-		s: NewSparseRangeStore(initialSize, 1.25),
+		b: NewRangeMapBuilder[rangeStoreValue](initialSize),
 	}
 }
 
-func (b *RangeStoreBuilder) Add(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) {
-	b.shouldSort = true
-
-	b.s.growBackingArraysIfNeeded()
-
-	b.s.from.Append(fromIncl)
-	b.s.end.Append(toIncl)
-	b.s.v1.Append(v1)
-	b.s.v2.Append(v2)
+func NewRangeStoreBuilderE(initialSize int) (RangeStoreBuilder, error) {
+	return NewRangeStoreBuilderWithOptions(initialSize, offheap.Options{})
 }
 
-func (b *RangeStoreBuilder) Build() RangeStore {
-	if b.shouldSort {
-		b.sort()
-	}
-
-	b.s.shrink()
-
-	return b.s
+// NewRangeStoreBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built store.
+func NewRangeStoreBuilderWithOptions(initialSize int, opts offheap.Options) (RangeStoreBuilder, error) {
+	b, err := NewRangeMapBuilderWithOptions[rangeStoreValue](initialSize, opts)
+	return RangeStoreBuilder{b: b}, err
 }
 
-func (b *RangeStoreBuilder) sort() {
-	sort.Sort(sparseRangeSorter(func() *RangeStore { return &b.s }))
-	b.shouldSort = false
+func (b *RangeStoreBuilder) Add(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) {
+	if err := b.AddE(fromIncl, toIncl, v1, v2); err != nil {
+		panic(err)
+	}
 }
 
-type sparseRangeSorter func() *RangeStore
-
-func (s sparseRangeSorter) Len() int {
-	return s().Size()
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (b *RangeStoreBuilder) AddE(fromIncl, toIncl _range.RangePoint, v1, v2 uint16) error {
+	return b.b.AddE(fromIncl, toIncl, rangeStoreValue{v1, v2})
 }
 
-func (s sparseRangeSorter) Less(i, j int) bool {
-	keys := s().from
-	return keys.Get(i) < keys.Get(j)
+func (b *RangeStoreBuilder) Build() RangeStore {
+	s, err := b.BuildE()
+	if err != nil {
+		panic(err)
+	}
+	return s
 }
 
-func (s sparseRangeSorter) Swap(i, j int) {
-	s().from.Swap(i, j)
-	s().end.Swap(i, j)
-	s().v1.Swap(i, j)
-	s().v2.Swap(i, j)
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *RangeStoreBuilder) BuildE() (RangeStore, error) {
+	m, err := b.b.BuildE()
+	return RangeStore{m: m}, err
 }
@@ -0,0 +1,157 @@
+package sparse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/andy722/structures/offheap"
+	"github.com/andy722/structures/range"
+)
+
+// PackedRangeStore maps inclusive range [fromIncl, toIncl] to a pair of values of a fixed bit width,
+// bit-packed into records of a RangeMap
+type PackedRangeStore struct {
+	m     RangeMap[[2]uint64]
+	width uint
+}
+
+// packedPayload packs a pair of values of width bits into the fewest bytes after the range end
+type packedPayload struct {
+	width uint
+}
+
+func (p packedPayload) size() int {
+	return int(2*p.width+7) / 8
+}
+
+func (p packedPayload) max() uint64 {
+	return ^uint64(0) >> (64 - p.width)
+}
+
+func (p packedPayload) Get(rec []byte) [2]uint64 {
+	var buf [16]byte
+	copy(buf[:], rec[rangeEndSize:rangeEndSize+p.size()])
+
+	lo, hi := binary.LittleEndian.Uint64(buf[:8]), binary.LittleEndian.Uint64(buf[8:])
+	return [2]uint64{lo & p.max(), (lo>>p.width | hi<<(64-p.width)) & p.max()}
+}
+
+func (p packedPayload) Put(rec []byte, v [2]uint64) {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], v[0]|v[1]<<p.width)
+	binary.LittleEndian.PutUint64(buf[8:], v[1]>>(64-p.width))
+
+	copy(rec[rangeEndSize:rangeEndSize+p.size()], buf[:])
+}
+
+func NewPackedRangeStore(width uint, initialSize int, grow float64) PackedRangeStore {
+	s, err := NewPackedRangeStoreE(width, initialSize, grow)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewPackedRangeStoreE is like NewPackedRangeStore, but returns an error if off-heap memory cannot be allocated
+func NewPackedRangeStoreE(width uint, initialSize int, grow float64) (PackedRangeStore, error) {
+	return NewPackedRangeStoreWithOptions(width, initialSize, grow, offheap.Options{})
+}
+
+// NewPackedRangeStoreWithOptions is like NewPackedRangeStoreE, with backing arrays mapped according to opts
+func NewPackedRangeStoreWithOptions(width uint, initialSize int, grow float64, opts offheap.Options) (PackedRangeStore, error) {
+	if width < 1 || width > 64 {
+		return PackedRangeStore{}, fmt.Errorf("sparse: packed width %d is not in range [1, 64]", width)
+	}
+
+	payload := packedPayload{width}
+	m, err := newRangeMap[[2]uint64](initialSize, growthOptions(opts, grow), payload.size(), payload)
+	return PackedRangeStore{m: m, width: width}, err
+}
+
+func (s *PackedRangeStore) Get(key ArrayUint64Key) (v1 uint64, v2 uint64, exists bool) {
+	v, exists := s.m.Get(key)
+	return v[0], v[1], exists
+}
+
+func (s *PackedRangeStore) ValuesV1(callback func(uint64)) {
+	rangeValues(&s.m, callback, func(v [2]uint64) uint64 { return v[0] })
+}
+
+func (s *PackedRangeStore) ValuesV2(callback func(uint64)) {
+	rangeValues(&s.m, callback, func(v [2]uint64) uint64 { return v[1] })
+}
+
+func (s *PackedRangeStore) Size() int {
+	return s.m.Size()
+}
+
+// Freeze makes the store read-only, see offheap.Array.Freeze
+func (s *PackedRangeStore) Freeze() error {
+	return s.m.Freeze()
+}
+
+// Frozen tells if the store was made read-only with Freeze
+func (s *PackedRangeStore) Frozen() bool {
+	return s.m.Frozen()
+}
+
+func (s *PackedRangeStore) Close() {
+	s.m.Close()
+}
+
+type PackedRangeStoreBuilder struct {
+	b     RangeMapBuilder[[2]uint64]
+	width uint
+}
+
+//goland:noinspection GoUnusedExportedFunction
+func NewPackedRangeStoreBuilder(width uint, initialSize int) PackedRangeStoreBuilder {
+	b, err := NewPackedRangeStoreBuilderE(width, initialSize)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func NewPackedRangeStoreBuilderE(width uint, initialSize int) (PackedRangeStoreBuilder, error) {
+	return NewPackedRangeStoreBuilderWithOptions(width, initialSize, offheap.Options{})
+}
+
+// NewPackedRangeStoreBuilderWithOptions creates a builder with backing arrays mapped according to opts.
+// Options are retained by the built store.
+func NewPackedRangeStoreBuilderWithOptions(width uint, initialSize int, opts offheap.Options) (PackedRangeStoreBuilder, error) {
+	s, err := NewPackedRangeStoreWithOptions(width, initialSize, DefaultGrow, opts)
+	return PackedRangeStoreBuilder{b: RangeMapBuilder[[2]uint64]{s: s.m}, width: width}, err
+}
+
+// Add maps [fromIncl, toIncl] to v1 and v2. Panics if a value does not fit in the width of the store.
+func (b *PackedRangeStoreBuilder) Add(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) {
+	if err := b.AddE(fromIncl, toIncl, v1, v2); err != nil {
+		panic(err)
+	}
+}
+
+// AddE is like Add, but returns an error if backing arrays cannot be grown
+func (b *PackedRangeStoreBuilder) AddE(fromIncl, toIncl _range.RangePoint, v1, v2 uint64) error {
+	if max := (packedPayload{b.width}).max(); v1 > max || v2 > max {
+		panic(fmt.Sprintf("sparse: values %d, %d do not fit in %d bits", v1, v2, b.width))
+	}
+
+	return b.b.AddE(fromIncl, toIncl, [2]uint64{v1, v2})
+}
+
+func (b *PackedRangeStoreBuilder) Build() PackedRangeStore {
+	s, err := b.BuildE()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// BuildE is like Build, but returns an error if backing arrays cannot be reallocated
+func (b *PackedRangeStoreBuilder) BuildE() (PackedRangeStore, error) {
+	m, err := b.b.BuildE()
+	if err != nil {
+		return PackedRangeStore{}, err
+	}
+	return PackedRangeStore{m: m, width: b.width}, nil
+}
@@ -0,0 +1,218 @@
+package trie
+
+import (
+	"fmt"
+	"github.com/andy722/structures/offheap"
+	"io"
+)
+
+const flatKind = "trie.Flat"
+
+// FlatTrie is a read-only Trie flattened into off-heap arrays, so that it can be written to a snapshot
+// and mapped from it. Values are of a fixed-size type V without pointers.
+type FlatTrie[V any] struct {
+	nodes  *offheap.Array[flatNode]
+	values *offheap.Array[V]
+}
+
+// flatNode refers to other nodes by index in FlatTrie.nodes, the root being at 0
+type flatNode struct {
+	children [10]uint32 // Child node for each digit, 0 if none
+	wildcard uint32     // Child node for a wildcard, 0 if none
+	value    uint32     // Index of a value plus 1, 0 if none
+}
+
+// Flatten copies trie into off-heap arrays. All values stored in trie must be of type V.
+func Flatten[V any](trie *Trie) (*FlatTrie[V], error) {
+	return FlattenWithOptions[V](trie, offheap.Options{})
+}
+
+// FlattenWithOptions is like Flatten, with backing arrays mapped according to opts
+func FlattenWithOptions[V any](trie *Trie, opts offheap.Options) (_ *FlatTrie[V], err error) {
+	// Number nodes breadth-first, so that siblings are stored close to each other
+	queue := []*Trie{trie}
+	index := map[*Trie]uint32{trie: 0}
+	nValues := 0
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		if node.value != nil {
+			nValues++
+		}
+		for _, child := range append(node.children[:], node.wildcard) {
+			if child != nil {
+				index[child] = uint32(len(queue))
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	t := &FlatTrie[V]{}
+	defer func() {
+		if err != nil {
+			t.Close()
+		}
+	}()
+
+	if t.nodes, err = offheap.NewArrayWithOptions[flatNode](len(queue), opts); err != nil {
+		return nil, err
+	}
+	if t.values, err = offheap.NewArrayWithOptions[V](nValues, opts); err != nil {
+		return nil, err
+	}
+
+	for _, node := range queue {
+		var flat flatNode
+		for d, child := range node.children {
+			if child != nil {
+				flat.children[d] = index[child]
+			}
+		}
+		if node.wildcard != nil {
+			flat.wildcard = index[node.wildcard]
+		}
+
+		if node.value != nil {
+			v, ok := node.value.(V)
+			if !ok {
+				return nil, fmt.Errorf("trie: value %v is not of type %T", node.value, v)
+			}
+			t.values.Append(v)
+			flat.value = uint32(t.values.Len())
+		}
+
+		t.nodes.Append(flat)
+	}
+
+	if err = t.nodes.Freeze(); err != nil {
+		return nil, err
+	}
+	if err = t.values.Freeze(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Lookup finds a value for key as Trie.Lookup does
+func (t *FlatTrie[V]) Lookup(key LookupKey) (val V, exists bool) {
+	if t.nodes.Len() == 0 {
+		return
+	}
+
+	digits := Digits(key)
+
+	// Try to force stack allocation
+	var stack []uint32
+	if digits < 20 {
+		stack = make([]uint32, 0, 2*20)
+	} else {
+		stack = make([]uint32, 0, digits)
+	}
+
+	stack = append(stack, 0)
+
+	for i := digits - 1; i >= 0; i-- {
+		r := Digit(key, i)
+
+		stackLen := len(stack)
+
+		for j := 0; j < stackLen; j++ {
+			node := t.nodes.Get(int(stack[j]))
+
+			if byMask := node.children[r]; byMask != 0 {
+				stack = append(stack, byMask)
+			}
+
+			if byWildcard := node.wildcard; byWildcard != 0 {
+				stack = append(stack, byWildcard)
+			}
+		}
+
+		stack = stack[stackLen:]
+	}
+
+	for _, c := range stack {
+		if v := t.nodes.Get(int(c)).value; v != 0 {
+			return t.values.Get(int(v - 1)), true
+		}
+	}
+
+	return
+}
+
+// Len returns the number of stored values
+func (t *FlatTrie[V]) Len() int {
+	return t.values.Len()
+}
+
+func (t *FlatTrie[V]) Close() {
+	if t.nodes != nil {
+		t.nodes.Dealloc()
+	}
+	if t.values != nil {
+		t.values.Dealloc()
+	}
+}
+
+// WriteTo writes a snapshot of the trie to w, which can be loaded with LoadFlatTrie or mapped with OpenFlatTrie
+func (t *FlatTrie[V]) WriteTo(w io.Writer) (int64, error) {
+	sw := offheap.NewSnapshotWriter(flatKind)
+	offheap.AddArrayColumn(sw, t.nodes)
+	offheap.AddArrayColumn(sw, t.values)
+	return sw.WriteTo(w)
+}
+
+// LoadFlatTrie reads a trie written by FlatTrie.WriteTo into off-heap memory mapped according to opts.
+// V must match the type of written values.
+func LoadFlatTrie[V any](r io.Reader, opts offheap.Options) (*FlatTrie[V], error) {
+	sr, err := offheap.NewSnapshotReader(r, flatKind)
+	if err != nil {
+		return nil, err
+	}
+	return readFlatTrie[V](sr, opts)
+}
+
+// OpenFlatTrie maps a snapshot file written by FlatTrie.WriteTo read-only and shared, see offheap.OpenSnapshot
+func OpenFlatTrie[V any](path string, opts offheap.Options) (*FlatTrie[V], error) {
+	sr, err := offheap.OpenSnapshot(path, flatKind)
+	if err != nil {
+		return nil, err
+	}
+	return readFlatTrie[V](sr, opts)
+}
+
+func readFlatTrie[V any](r *offheap.SnapshotReader, opts offheap.Options) (_ *FlatTrie[V], err error) {
+	t := &FlatTrie[V]{}
+	defer func() {
+		if err != nil {
+			t.Close()
+		}
+	}()
+
+	if t.nodes, err = offheap.ReadArrayColumn[flatNode](r, opts); err != nil {
+		return nil, err
+	}
+	if t.values, err = offheap.ReadArrayColumn[V](r, opts); err != nil {
+		return nil, err
+	}
+
+	// Check references, so that a lookup can't run out of bounds
+	for i := 0; i < t.nodes.Len(); i++ {
+		node := t.nodes.Get(i)
+		for _, child := range append(node.children[:], node.wildcard) {
+			if int(child) >= t.nodes.Len() {
+				return nil, fmt.Errorf("%w: trie node %d refers to missing node %d", offheap.ErrSnapshot, i, child)
+			}
+		}
+		if int(node.value) > t.values.Len() {
+			return nil, fmt.Errorf("%w: trie node %d refers to missing value %d", offheap.ErrSnapshot, i, node.value)
+		}
+	}
+
+	if err = t.nodes.Freeze(); err != nil {
+		return nil, err
+	}
+	if err = t.values.Freeze(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package trie
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andy722/structures/offheap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatTrie(t *testing.T) {
+	s := NewTrie()
+	s.Put(MustParseMask("7944???????"), uint32(1))
+	s.Put(MustParseMask("79440??????"), uint32(2))
+	s.Put(MustParseMask("79440001103"), uint32(3))
+
+	flat, err := Flatten[uint32](s)
+	require.NoError(t, err)
+	defer flat.Close()
+
+	check := func(flat *FlatTrie[uint32]) {
+		assert.Equal(t, 3, flat.Len())
+		for _, key := range []LookupKey{79441001101, 79440001101, 79440000001, 79440001103, 79450000000} {
+			expected, ok := s.Lookup(key).(uint32)
+			v, exists := flat.Lookup(key)
+			assert.Equal(t, ok, exists, key)
+			assert.Equal(t, expected, v, key)
+		}
+	}
+	check(flat)
+
+	path := filepath.Join(t.TempDir(), "trie")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	_, err = flat.WriteTo(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	opened, err := OpenFlatTrie[uint32](path, offheap.Options{})
+	require.NoError(t, err)
+	defer opened.Close()
+	check(opened)
+
+	f, err = os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	loaded, err := LoadFlatTrie[uint32](f, offheap.Options{})
+	require.NoError(t, err)
+	defer loaded.Close()
+	check(loaded)
+
+	_, err = Flatten[uint64](s)
+	assert.Error(t, err)
+
+	empty, err := Flatten[uint32](NewTrie())
+	require.NoError(t, err)
+	defer empty.Close()
+	_, exists := empty.Lookup(79440001103)
+	assert.False(t, exists)
+}